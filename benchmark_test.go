@@ -0,0 +1,75 @@
+package flog_test
+
+import (
+	"github.com/buexplain/go-flog"
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/formatter"
+	"io"
+	"testing"
+)
+
+// 格式化后丢弃日志的处理器，用于测量日志收集流程本身的开销
+type discardHandler struct {
+	formatter contract.Formatter
+}
+
+func (r *discardHandler) Handle(record *contract.Record) bool {
+	_, _ = r.formatter.ToWriter(io.Discard, record)
+	return false
+}
+
+func (r *discardHandler) IsHandling(level contract.Level) bool {
+	return true
+}
+
+func (r *discardHandler) Close() error {
+	return nil
+}
+
+func newBenchmarkLogger(f contract.Formatter) *flog.Logger {
+	logger := flog.New("benchmark", nil)
+	logger.PushHandler(&discardHandler{formatter: f})
+	return logger
+}
+
+func BenchmarkLoggerLine(b *testing.B) {
+	logger := newBenchmarkLogger(formatter.NewLine())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info("benchmark message", flog.String("user", "刘备"), flog.Int("age", 28))
+	}
+}
+
+func BenchmarkLoggerJSON(b *testing.B) {
+	logger := newBenchmarkLogger(formatter.NewJSON())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info("benchmark message", flog.String("user", "刘备"), flog.Int("age", 28))
+	}
+}
+
+func BenchmarkLoggerJSONParallel(b *testing.B) {
+	logger := newBenchmarkLogger(formatter.NewJSON())
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			logger.Info("benchmark message", flog.String("user", "刘备"), flog.Int("age", 28))
+		}
+	})
+}
+
+func BenchmarkLoggerAsyncJSON(b *testing.B) {
+	logger := newBenchmarkLogger(formatter.NewJSON())
+	logger.Async(1024)
+	defer func() {
+		_ = logger.Close()
+	}()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info("benchmark message", flog.String("user", "刘备"), flog.Int("age", 28))
+	}
+}
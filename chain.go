@@ -0,0 +1,61 @@
+package flog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/buexplain/go-flog/contract"
+)
+
+// 按顺序调用日志处理器集合的日志处理器，用于共用写入go程的异步模式
+// 只由写入go程调用，不对外暴露，日志处理器的关闭由日志收集器负责
+type chainHandler struct {
+	logger   *Logger
+	handlers []contract.Handler
+}
+
+func newChainHandler(logger *Logger, handlers []contract.Handler) *chainHandler {
+	tmp := new(chainHandler)
+	tmp.logger = logger
+	tmp.handlers = append(make([]contract.Handler, 0, len(handlers)), handlers...)
+	return tmp
+}
+
+func (r *chainHandler) Handle(record *contract.Record) bool {
+	r.logger.dispatch(r.handlers, record)
+	return true
+}
+
+func (r *chainHandler) IsHandling(level contract.Level) bool {
+	for _, v := range r.handlers {
+		if v.IsHandling(level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *chainHandler) Close() error {
+	return nil
+}
+
+// Flush 冲刷实现了 contract.Flusher 的日志处理器
+func (r *chainHandler) Flush(ctx context.Context) error {
+	bag := bytes.Buffer{}
+	for _, v := range r.handlers {
+		if flusher, ok := v.(contract.Flusher); ok {
+			if e := flusher.Flush(ctx); e != nil {
+				bag.WriteString(e.Error())
+				bag.WriteByte('\n')
+			}
+		}
+	}
+	if bag.Len() > 0 {
+		return errors.New(bag.String())
+	}
+	return nil
+}
+
+func (r *chainHandler) GetName() string {
+	return "chain"
+}
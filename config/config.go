@@ -0,0 +1,196 @@
+// Package config 从配置文档构建日志收集器
+// 只内置了 JSON 的解码函数，YAML、TOML 等其它格式需要先通过 RegisterDecoder 注册解码函数，否则解析时返回 unknown config format 错误
+// 配置结构体只声明了 json 标签，解码函数需要按 json 标签匹配字段，最简单的做法是先将文档转换为 JSON 再调用 json.Unmarshal
+// 比如借助 sigs.k8s.io/yaml 注册 YAML 的解码函数：
+//
+//	decoder := func(data []byte, v interface{}) error {
+//		j, err := yaml.YAMLToJSON(data)
+//		if err != nil {
+//			return err
+//		}
+//		return json.Unmarshal(j, v)
+//	}
+//	config.RegisterDecoder("yaml", decoder)
+//	config.RegisterDecoder("yml", decoder)
+//	cfg, err := config.ParseFile("log.yaml")
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/buexplain/go-flog/contract"
+	"time"
+)
+
+// Config 日志配置
+type Config struct {
+	//默认日志收集器的渠道名称，为空则不替换默认日志收集器
+	Default string `json:"default"`
+	//各个渠道的日志收集器
+	Loggers []LoggerConfig `json:"loggers"`
+}
+
+// LoggerConfig 日志收集器配置
+type LoggerConfig struct {
+	//渠道名称
+	Channel string `json:"channel"`
+	//异步日志队列容量，0表示同步调度日志
+	Async int `json:"async"`
+	//异步模式下是否所有日志处理器共用一个写入go程，保留 Handle 返回值阻止日志进入下一个日志处理器的语义
+	AsyncChain bool `json:"asyncChain"`
+	//日志处理器，按顺序处理日志
+	Handlers []HandlerConfig `json:"handlers"`
+	//额外日志信息处理器
+	Extras []ExtraConfig `json:"extras"`
+}
+
+// HandlerConfig 日志处理器配置
+type HandlerConfig struct {
+	//日志处理器类型，比如：file、http、std、dingtalk
+	Type string `json:"type"`
+	//日志处理器名称，为空则使用日志处理器的默认名称
+	Name string `json:"name"`
+	//日志等级，为空则为 debug
+	Level string `json:"level"`
+	//是否阻止进入下一个日志处理器
+	Bubble bool `json:"bubble"`
+	//日志格式化处理器，为空则由日志处理器决定
+	Formatter *FormatterConfig `json:"formatter"`
+	//日志处理器类型特有的选项
+	Options Options `json:"options"`
+}
+
+// FormatterConfig 日志格式化处理器配置
+type FormatterConfig struct {
+	//格式化处理器类型，比如：line、json、dingtalk_text
+	Type string `json:"type"`
+	//格式化处理器类型特有的选项
+	Options Options `json:"options"`
+}
+
+// ExtraConfig 额外日志信息处理器配置
+type ExtraConfig struct {
+	//额外日志信息处理器类型，比如：func_caller、ip、request_id、trace_parent
+	Type string `json:"type"`
+	//额外日志信息处理器类型特有的选项
+	Options Options `json:"options"`
+}
+
+// GetLevel 解析日志等级
+func (r HandlerConfig) GetLevel() (contract.Level, error) {
+	if r.Level == "" {
+		return contract.LevelDebug, nil
+	}
+	level, ok := contract.ParseLevel(r.Level)
+	if !ok {
+		return level, fmt.Errorf("invalid level: %s", r.Level)
+	}
+	return level, nil
+}
+
+// Options 各个类型特有的选项，取值方法在选项不存在时返回默认值，类型不符时返回错误
+type Options map[string]interface{}
+
+func (r Options) String(key string, def string) (string, error) {
+	v, ok := r[key]
+	if !ok || v == nil {
+		return def, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return def, fmt.Errorf("option %s: expected string, got %T", key, v)
+	}
+	return s, nil
+}
+
+func (r Options) Bool(key string, def bool) (bool, error) {
+	v, ok := r[key]
+	if !ok || v == nil {
+		return def, nil
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return def, fmt.Errorf("option %s: expected bool, got %T", key, v)
+	}
+	return b, nil
+}
+
+// Int64 兼容各个解码器解码出的整数与浮点数
+func (r Options) Int64(key string, def int64) (int64, error) {
+	v, ok := r[key]
+	if !ok || v == nil {
+		return def, nil
+	}
+	switch n := v.(type) {
+	case int:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case uint64:
+		return int64(n), nil
+	case float64:
+		if n != float64(int64(n)) {
+			return def, fmt.Errorf("option %s: expected integer, got %v", key, n)
+		}
+		return int64(n), nil
+	case json.Number:
+		return n.Int64()
+	default:
+		return def, fmt.Errorf("option %s: expected integer, got %T", key, v)
+	}
+}
+
+func (r Options) Int(key string, def int) (int, error) {
+	n, err := r.Int64(key, int64(def))
+	return int(n), err
+}
+
+// Duration 时长选项的格式为 time.ParseDuration 支持的字符串，比如：1s、500ms
+func (r Options) Duration(key string, def time.Duration) (time.Duration, error) {
+	s, err := r.String(key, "")
+	if err != nil || s == "" {
+		return def, err
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return def, fmt.Errorf("option %s: %w", key, err)
+	}
+	return d, nil
+}
+
+// Decode 将选项解码到第三方日志处理器自己的配置结构体，按 json 标签匹配字段
+func (r Options) Decode(v interface{}) error {
+	data, err := json.Marshal(normalize(map[string]interface{}(r)))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// 将 YAML 解码器产生的 map[interface{}]interface{} 转换为 map[string]interface{}
+func normalize(v interface{}) interface{} {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		tmp := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			tmp[k] = normalize(v)
+		}
+		return tmp
+	case Options:
+		return normalize(map[string]interface{}(m))
+	case map[interface{}]interface{}:
+		tmp := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			tmp[fmt.Sprint(k)] = normalize(v)
+		}
+		return tmp
+	case []interface{}:
+		tmp := make([]interface{}, len(m))
+		for i, v := range m {
+			tmp[i] = normalize(v)
+		}
+		return tmp
+	default:
+		return v
+	}
+}
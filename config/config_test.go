@@ -0,0 +1,167 @@
+package config_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/buexplain/go-flog"
+	"github.com/buexplain/go-flog/config"
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/handler"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const document = `{
+	"default": "app",
+	"loggers": [
+		{
+			"channel": "app",
+			"handlers": [
+				{"type": "file", "name": "app-file", "level": "info", "bubble": true, "formatter": {"type": "json"}, "options": {"path": "%s", "buffer": 4096, "flush": "100ms", "maxSize": 1048576, "prefix": "app"}},
+				{"type": "std", "level": "error", "options": {"dst": "stdout"}}
+			],
+			"extras": [{"type": "func_caller"}, {"type": "ip"}, {"type": "request_id"}]
+		},
+		{
+			"channel": "payment",
+			"async": 100,
+			"handlers": [
+				{"type": "http", "level": "error", "options": {"url": "http://127.0.0.1:1/log", "timeout": "1s", "header": {"X-Token": "abc"}}},
+				{"type": "dingtalk", "level": "critical", "options": {"robots": [{"url": "http://127.0.0.1:1/robot", "secret": "s"}], "compress": true}}
+			]
+		}
+	]
+}`
+
+func TestConfig(t *testing.T) {
+	path, err := os.MkdirTemp("", "flog-config")
+	if err != nil {
+		t.Fatal("构建临时目录失败", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(path)
+	}()
+	data := strings.Replace(document, "%s", filepath.ToSlash(path), 1)
+	//注册其它格式的解码函数，这里用 json 代替 yaml
+	config.RegisterDecoder("yaml", json.Unmarshal)
+	cfg, err := config.Parse([]byte(data), "yaml")
+	if err != nil {
+		t.Fatal("解析配置失败", err)
+	}
+	old := flog.Default()
+	defer flog.SetDefault(old)
+	loggers, err := cfg.Apply()
+	if err != nil {
+		t.Fatal("按配置创建日志收集器失败", err)
+	}
+	app := loggers["app"]
+	if flog.Default() != app || flog.Get("payment") != loggers["payment"] {
+		t.Error("没有注册共享日志收集器或者替换默认日志收集器")
+	}
+	handlers := app.GetHandlers()
+	if len(handlers) != 2 || handlers[0].(*handler.File).GetName() != "app-file" || handlers[0].(*handler.File).GetLevel() != contract.LevelInfo {
+		t.Error("日志处理器配置错误", handlers)
+	}
+	if len(app.GetExtras()) != 2 || len(app.GetContextExtras()) != 1 {
+		t.Error("额外日志信息处理器配置错误")
+	}
+	if h := loggers["payment"].GetHandlers(); len(h) != 2 || h[0].(*handler.HTTP).GetLevel() != contract.LevelError {
+		t.Error("日志处理器配置错误", h)
+	}
+	app.Info("from config")
+	app.Debug("ignored")
+	if err := app.Flush(context.Background()); err != nil {
+		t.Error("冲刷日志失败", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(path, "app-*.log"))
+	if len(files) != 1 {
+		t.Fatal("没有按配置写入日志文件", files)
+	}
+	content, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(content), `"Message":"from config"`) || strings.Contains(string(content), "ignored") || !strings.Contains(string(content), "config_test.go") {
+		t.Error("日志文件内容错误", string(content))
+	}
+	for _, logger := range loggers {
+		_ = logger.Close()
+	}
+	flog.Register("app", nil)
+	flog.Register("payment", nil)
+}
+
+// 第三方日志处理器
+type memoryHandler struct {
+	lock    *sync.Mutex
+	prefix  string
+	records []string
+}
+
+func (r *memoryHandler) Handle(record *contract.Record) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.records = append(r.records, r.prefix+record.Message)
+	return false
+}
+
+func (r *memoryHandler) IsHandling(level contract.Level) bool {
+	return true
+}
+
+func (r *memoryHandler) Close() error {
+	return nil
+}
+
+func TestConfigRegisterHandler(t *testing.T) {
+	config.RegisterHandler("memory", func(cfg config.HandlerConfig) (contract.Handler, error) {
+		var options struct {
+			Prefix string `json:"prefix"`
+		}
+		if err := cfg.Options.Decode(&options); err != nil {
+			return nil, err
+		}
+		return &memoryHandler{lock: new(sync.Mutex), prefix: options.Prefix}, nil
+	})
+	//YAML 解码器产生的 map[interface{}]interface{} 也可以解码
+	cfg := &config.Config{Loggers: []config.LoggerConfig{{
+		Channel:  "memory",
+		Handlers: []config.HandlerConfig{{Type: "memory", Options: config.Options{"prefix": "> ", "nested": map[interface{}]interface{}{"a": 1}}}},
+	}}}
+	loggers, err := cfg.Build()
+	if err != nil {
+		t.Fatal("按配置创建第三方日志处理器失败", err)
+	}
+	loggers["memory"].Info("third party")
+	h := loggers["memory"].GetHandlers()[0].(*memoryHandler)
+	if len(h.records) != 1 || h.records[0] != "> third party" {
+		t.Error("第三方日志处理器配置错误", h.records)
+	}
+}
+
+func TestConfigError(t *testing.T) {
+	tests := map[string]string{
+		`{"loggers":[{"channel":"a","handlers":[{"type":"unknown"}]}]}`:                                    "unknown handler type",
+		`{"loggers":[{"channel":"a","handlers":[{"type":"std","level":"verbose"}]}]}`:                      "invalid level",
+		`{"loggers":[{"channel":"a","handlers":[{"type":"std","formatter":{"type":"xml"}}]}]}`:             "unknown formatter type",
+		`{"loggers":[{"channel":"a","handlers":[{"type":"file","options":{"path":1}}]}]}`:                  "expected string",
+		`{"loggers":[{"channel":"a","handlers":[{"type":"file","options":{"path":"x","prefix":"a-b"}}]}]}`: "invalid prefix",
+		`{"loggers":[{"channel":"a","extras":[{"type":"unknown"}]}]}`:                                      "unknown extra type",
+		`{"loggers":[{"channel":"a"},{"channel":"a"}]}`:                                                    "duplicate channel",
+		`{"default":"b","loggers":[{"channel":"a"}]}`:                                                      "default channel not found",
+	}
+	for data, expect := range tests {
+		cfg, err := config.Parse([]byte(data), "json")
+		if err != nil {
+			t.Error("解析配置失败", err)
+			continue
+		}
+		if _, err = cfg.Build(); err == nil || !strings.Contains(err.Error(), expect) {
+			t.Errorf("期待错误包含 %s，实际错误是 %v", expect, err)
+		}
+	}
+	//未注册的配置格式提示先注册解码函数
+	if _, err := config.Parse([]byte("{}"), "ini"); err == nil || !strings.Contains(err.Error(), "RegisterDecoder") {
+		t.Error("未注册的配置格式应该返回错误", err)
+	}
+}
@@ -0,0 +1,403 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/extra"
+	"github.com/buexplain/go-flog/formatter"
+	"github.com/buexplain/go-flog/handler"
+	dingtalk "github.com/buexplain/go-flog/handler/dingTalk"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HandlerFactory 按配置创建日志处理器
+type HandlerFactory func(config HandlerConfig) (contract.Handler, error)
+
+// FormatterFactory 按选项创建日志格式化处理器
+type FormatterFactory func(options Options) (contract.Formatter, error)
+
+// ExtraFactory 按选项创建额外日志信息处理器，返回值必须实现 contract.Extra 或者 contract.ContextExtra
+type ExtraFactory func(options Options) (interface{}, error)
+
+// Decoder 配置文档的解码函数，签名与 json.Unmarshal 一致，需要按 json 标签匹配字段
+type Decoder func(data []byte, v interface{}) error
+
+// 各个类型的工厂函数
+var factories = struct {
+	lock       *sync.RWMutex
+	handlers   map[string]HandlerFactory
+	formatters map[string]FormatterFactory
+	extras     map[string]ExtraFactory
+	decoders   map[string]Decoder
+}{
+	lock:       new(sync.RWMutex),
+	handlers:   map[string]HandlerFactory{},
+	formatters: map[string]FormatterFactory{},
+	extras:     map[string]ExtraFactory{},
+	decoders:   map[string]Decoder{},
+}
+
+func init() {
+	RegisterHandler("file", newFile)
+	RegisterHandler("http", newHTTP)
+	RegisterHandler("std", newSTD)
+	RegisterHandler("dingtalk", newDingTalk)
+	RegisterFormatter("line", newLine)
+	RegisterFormatter("json", newJSON)
+	RegisterFormatter("dingtalk_text", newDingTalkText)
+	RegisterExtra("func_caller", newFuncCaller)
+	RegisterExtra("ip", newIP)
+	RegisterExtra("request_id", newRequestID)
+	RegisterExtra("trace_parent", newTraceParent)
+	RegisterDecoder("json", json.Unmarshal)
+}
+
+// RegisterHandler 注册日志处理器类型，同名类型会被替换，第三方日志处理器可以通过 Options.Decode 解码自己的配置
+func RegisterHandler(typ string, factory HandlerFactory) {
+	factories.lock.Lock()
+	defer factories.lock.Unlock()
+	factories.handlers[typ] = factory
+}
+
+// RegisterFormatter 注册日志格式化处理器类型，同名类型会被替换
+func RegisterFormatter(typ string, factory FormatterFactory) {
+	factories.lock.Lock()
+	defer factories.lock.Unlock()
+	factories.formatters[typ] = factory
+}
+
+// RegisterExtra 注册额外日志信息处理器类型，同名类型会被替换
+func RegisterExtra(typ string, factory ExtraFactory) {
+	factories.lock.Lock()
+	defer factories.lock.Unlock()
+	factories.extras[typ] = factory
+}
+
+// RegisterDecoder 注册配置文档格式的解码函数，格式名称即配置文件的扩展名，比如：yaml、yml
+// 比如先将 YAML 转换为 JSON 再调用 json.Unmarshal 的解码函数
+func RegisterDecoder(format string, decoder Decoder) {
+	factories.lock.Lock()
+	defer factories.lock.Unlock()
+	factories.decoders[strings.ToLower(format)] = decoder
+}
+
+// NewHandler 按配置创建日志处理器
+func NewHandler(config HandlerConfig) (contract.Handler, error) {
+	factories.lock.RLock()
+	factory, ok := factories.handlers[config.Type]
+	factories.lock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown handler type: %s", config.Type)
+	}
+	h, err := factory(config)
+	if err != nil {
+		return nil, fmt.Errorf("handler %s: %w", config.Type, err)
+	}
+	return h, nil
+}
+
+// NewFormatter 按配置创建日志格式化处理器，config 为 nil 时创建 line 格式化处理器
+func NewFormatter(config *FormatterConfig) (contract.Formatter, error) {
+	if config == nil {
+		config = &FormatterConfig{Type: "line"}
+	}
+	factories.lock.RLock()
+	factory, ok := factories.formatters[config.Type]
+	factories.lock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown formatter type: %s", config.Type)
+	}
+	f, err := factory(config.Options)
+	if err != nil {
+		return nil, fmt.Errorf("formatter %s: %w", config.Type, err)
+	}
+	return f, nil
+}
+
+// NewExtra 按配置创建额外日志信息处理器，返回值是 contract.Extra 或者 contract.ContextExtra
+func NewExtra(config ExtraConfig) (interface{}, error) {
+	factories.lock.RLock()
+	factory, ok := factories.extras[config.Type]
+	factories.lock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown extra type: %s", config.Type)
+	}
+	e, err := factory(config.Options)
+	if err != nil {
+		return nil, fmt.Errorf("extra %s: %w", config.Type, err)
+	}
+	switch e.(type) {
+	case contract.Extra, contract.ContextExtra:
+		return e, nil
+	default:
+		return nil, fmt.Errorf("extra %s: %T implements neither contract.Extra nor contract.ContextExtra", config.Type, e)
+	}
+}
+
+// 日志文件前缀的格式，与 handler.File.SetPrefix 一致
+var prefixRegexp = regexp.MustCompile(`^[A-Za-z_0-9]+$`)
+
+// file 日志处理器，选项：path（日志目录）、buffer、flush、maxSize、prefix、perm
+func newFile(config HandlerConfig) (contract.Handler, error) {
+	level, err := config.GetLevel()
+	if err != nil {
+		return nil, err
+	}
+	f, err := NewFormatter(config.Formatter)
+	if err != nil {
+		return nil, err
+	}
+	path, err := config.Options.String("path", "")
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return nil, fmt.Errorf("option path is required")
+	}
+	buffer, err := config.Options.Int("buffer", 0)
+	if err != nil {
+		return nil, err
+	}
+	flush, err := config.Options.Duration("flush", time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if buffer > 0 && flush <= 0 {
+		return nil, fmt.Errorf("option flush must be positive")
+	}
+	maxSize, err := config.Options.Int64("maxSize", -1)
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := config.Options.String("prefix", "")
+	if err != nil {
+		return nil, err
+	}
+	if prefix != "" && !prefixRegexp.MatchString(prefix) {
+		return nil, fmt.Errorf("invalid prefix: %s", prefix)
+	}
+	//权限为八进制字符串，比如：0644
+	perm, err := config.Options.String("perm", "")
+	if err != nil {
+		return nil, err
+	}
+	var mode uint64
+	if perm != "" {
+		if mode, err = strconv.ParseUint(perm, 8, 32); err != nil {
+			return nil, fmt.Errorf("invalid perm: %s", perm)
+		}
+	}
+	tmp := handler.NewFile(level, f, path)
+	tmp.SetBubble(config.Bubble)
+	if config.Name != "" {
+		tmp.SetName(config.Name)
+	}
+	if maxSize >= 0 {
+		tmp.SetMaxSize(maxSize)
+	}
+	if prefix != "" {
+		tmp.SetPrefix(prefix)
+	}
+	if perm != "" {
+		tmp.SetPerm(os.FileMode(mode))
+	}
+	if buffer > 0 {
+		tmp.SetBuffer(buffer, flush)
+	}
+	return tmp, nil
+}
+
+// http 日志处理器，选项：url、timeout、header
+func newHTTP(config HandlerConfig) (contract.Handler, error) {
+	level, err := config.GetLevel()
+	if err != nil {
+		return nil, err
+	}
+	f, err := NewFormatter(config.Formatter)
+	if err != nil {
+		return nil, err
+	}
+	var options struct {
+		URL    string            `json:"url"`
+		Header map[string]string `json:"header"`
+	}
+	if err = config.Options.Decode(&options); err != nil {
+		return nil, err
+	}
+	if options.URL == "" {
+		return nil, fmt.Errorf("option url is required")
+	}
+	timeout, err := config.Options.Duration("timeout", 0)
+	if err != nil {
+		return nil, err
+	}
+	tmp := handler.NewHTTP(level, f, options.URL).SetBubble(config.Bubble)
+	if config.Name != "" {
+		tmp.SetName(config.Name)
+	}
+	if timeout > 0 {
+		tmp.SetTimeout(timeout)
+	}
+	if len(options.Header) > 0 {
+		header := http.Header{}
+		for k, v := range options.Header {
+			header.Set(k, v)
+		}
+		tmp.SetHeader(header)
+	}
+	return tmp, nil
+}
+
+// std 日志处理器，选项：dst，等于或高于该等级的日志写入标准错误，默认为 error，stdout 表示全部写入标准输出
+func newSTD(config HandlerConfig) (contract.Handler, error) {
+	level, err := config.GetLevel()
+	if err != nil {
+		return nil, err
+	}
+	f, err := NewFormatter(config.Formatter)
+	if err != nil {
+		return nil, err
+	}
+	dstName, err := config.Options.String("dst", "error")
+	if err != nil {
+		return nil, err
+	}
+	dst := contract.Level(-1)
+	if dstName != "stdout" {
+		var ok bool
+		if dst, ok = contract.ParseLevel(dstName); !ok {
+			return nil, fmt.Errorf("invalid dst: %s", dstName)
+		}
+	}
+	tmp := handler.NewSTD(level, f, dst).SetBubble(config.Bubble)
+	if config.Name != "" {
+		tmp.SetName(config.Name)
+	}
+	return tmp, nil
+}
+
+// dingtalk 日志处理器，选项：robots、compress，robots 的每一项包含 url、secret、capacity
+// 没有配置格式化处理器时使用 dingtalk_text 格式化处理器
+func newDingTalk(config HandlerConfig) (contract.Handler, error) {
+	level, err := config.GetLevel()
+	if err != nil {
+		return nil, err
+	}
+	formatterConfig := config.Formatter
+	if formatterConfig == nil {
+		formatterConfig = &FormatterConfig{Type: "dingtalk_text"}
+	}
+	f, err := NewFormatter(formatterConfig)
+	if err != nil {
+		return nil, err
+	}
+	var options struct {
+		Robots []struct {
+			URL      string `json:"url"`
+			Secret   string `json:"secret"`
+			Capacity int    `json:"capacity"`
+		} `json:"robots"`
+		Compress bool `json:"compress"`
+	}
+	if err = config.Options.Decode(&options); err != nil {
+		return nil, err
+	}
+	if len(options.Robots) == 0 {
+		return nil, fmt.Errorf("option robots is required")
+	}
+	for _, v := range options.Robots {
+		if v.URL == "" {
+			return nil, fmt.Errorf("option robots.url is required")
+		}
+	}
+	robots := make([]*dingtalk.Robot, 0, len(options.Robots))
+	for _, v := range options.Robots {
+		if v.Capacity <= 0 {
+			v.Capacity = 100
+		}
+		robots = append(robots, dingtalk.NewRobot(v.URL, v.Secret, f, v.Capacity))
+	}
+	tmp := dingtalk.New(level, robots, options.Compress)
+	if config.Name != "" {
+		tmp.SetName(config.Name)
+	}
+	return tmp, nil
+}
+
+// line 格式化处理器，选项：timeFormat
+func newLine(options Options) (contract.Formatter, error) {
+	timeFormat, err := options.String("timeFormat", "")
+	if err != nil {
+		return nil, err
+	}
+	tmp := formatter.NewLine()
+	if timeFormat != "" {
+		tmp.SetTimeFormat(timeFormat)
+	}
+	return tmp, nil
+}
+
+// json 格式化处理器，选项：escapeHTML、prefix、indent
+func newJSON(options Options) (contract.Formatter, error) {
+	escapeHTML, err := options.Bool("escapeHTML", true)
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := options.String("prefix", "")
+	if err != nil {
+		return nil, err
+	}
+	indent, err := options.String("indent", "")
+	if err != nil {
+		return nil, err
+	}
+	tmp := formatter.NewJSON().SetEscapeHTML(escapeHTML)
+	if prefix != "" || indent != "" {
+		tmp.SetIndent(prefix, indent)
+	}
+	return tmp, nil
+}
+
+// dingtalk_text 格式化处理器，选项：atMobiles、isAtAll
+func newDingTalkText(options Options) (contract.Formatter, error) {
+	var tmp struct {
+		AtMobiles []string `json:"atMobiles"`
+		IsAtAll   bool     `json:"isAtAll"`
+	}
+	if err := options.Decode(&tmp); err != nil {
+		return nil, err
+	}
+	f := dingtalk.NewFormatText().SetIsAtAll(tmp.IsAtAll)
+	for _, mobile := range tmp.AtMobiles {
+		f.SetAtMobile(mobile)
+	}
+	return f, nil
+}
+
+// func_caller 额外日志信息处理器，选项：skip
+func newFuncCaller(options Options) (interface{}, error) {
+	skip, err := options.Int("skip", 3)
+	if err != nil {
+		return nil, err
+	}
+	return extra.NewFuncCaller(skip), nil
+}
+
+func newIP(options Options) (interface{}, error) {
+	return extra.NewIP(), nil
+}
+
+func newRequestID(options Options) (interface{}, error) {
+	return extra.NewRequestID(), nil
+}
+
+func newTraceParent(options Options) (interface{}, error) {
+	return extra.NewTraceParent(), nil
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"fmt"
+	"github.com/buexplain/go-flog"
+	"github.com/buexplain/go-flog/contract"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Parse 按格式解码配置文档，格式需要先通过 RegisterDecoder 注册，json 是内置的
+func Parse(data []byte, format string) (*Config, error) {
+	factories.lock.RLock()
+	decoder, ok := factories.decoders[strings.ToLower(format)]
+	factories.lock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown config format: %s, register a decoder with RegisterDecoder first", format)
+	}
+	tmp := new(Config)
+	if err := decoder(data, tmp); err != nil {
+		return nil, fmt.Errorf("decode %s config: %w", format, err)
+	}
+	return tmp, nil
+}
+
+// ParseFile 读取配置文件，按扩展名选择解码函数
+func ParseFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data, strings.TrimPrefix(filepath.Ext(path), "."))
+}
+
+// 一个渠道的日志处理器与额外日志信息处理器
+type components struct {
+	handlers      []contract.Handler
+	extras        []contract.Extra
+	contextExtras []contract.ContextExtra
+}
+
+// 关闭已经创建的日志处理器
+func (r *components) close() {
+	for _, h := range r.handlers {
+		_ = h.Close()
+	}
+}
+
+// 按配置创建一个渠道的日志处理器与额外日志信息处理器，失败时关闭已经创建的日志处理器
+func build(config LoggerConfig) (*components, error) {
+	tmp := new(components)
+	for _, v := range config.Extras {
+		e, err := NewExtra(v)
+		if err != nil {
+			return nil, err
+		}
+		if extra, ok := e.(contract.Extra); ok {
+			tmp.extras = append(tmp.extras, extra)
+		}
+		if extra, ok := e.(contract.ContextExtra); ok {
+			tmp.contextExtras = append(tmp.contextExtras, extra)
+		}
+	}
+	for _, v := range config.Handlers {
+		h, err := NewHandler(v)
+		if err != nil {
+			tmp.close()
+			return nil, err
+		}
+		tmp.handlers = append(tmp.handlers, h)
+	}
+	return tmp, nil
+}
+
+// 校验渠道名称不重复，默认日志收集器的渠道存在
+func (r *Config) validate() error {
+	channels := make(map[string]struct{}, len(r.Loggers))
+	for _, v := range r.Loggers {
+		if _, ok := channels[v.Channel]; ok {
+			return fmt.Errorf("duplicate channel: %s", v.Channel)
+		}
+		channels[v.Channel] = struct{}{}
+	}
+	if _, ok := channels[r.Default]; r.Default != "" && !ok {
+		return fmt.Errorf("default channel not found: %s", r.Default)
+	}
+	return nil
+}
+
+// Build 按配置创建各个渠道的日志收集器，任意一个渠道失败则关闭已经创建的日志处理器并返回错误
+func (r *Config) Build() (map[string]*flog.Logger, error) {
+	built, err := r.build()
+	if err != nil {
+		return nil, err
+	}
+	loggers := make(map[string]*flog.Logger, len(r.Loggers))
+	for i, v := range r.Loggers {
+		loggers[v.Channel] = newLogger(v, built[i])
+	}
+	return loggers, nil
+}
+
+// 校验配置并创建各个渠道的日志处理器与额外日志信息处理器，任意一个渠道失败则关闭已经创建的日志处理器
+func (r *Config) build() ([]*components, error) {
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+	built := make([]*components, 0, len(r.Loggers))
+	for _, v := range r.Loggers {
+		c, err := build(v)
+		if err != nil {
+			for _, c := range built {
+				c.close()
+			}
+			return nil, fmt.Errorf("channel %s: %w", v.Channel, err)
+		}
+		built = append(built, c)
+	}
+	return built, nil
+}
+
+func newLogger(config LoggerConfig, c *components) *flog.Logger {
+	logger := flog.New(config.Channel, nil, c.extras...)
+	logger.SetHandlers(c.handlers...)
+	for _, extra := range c.contextExtras {
+		logger.PushContextExtra(extra)
+	}
+	if config.Async > 0 {
+		if config.AsyncChain {
+			logger.AsyncChain(config.Async)
+		} else {
+			logger.Async(config.Async)
+		}
+	}
+	return logger
+}
+
+// Apply 按配置创建各个渠道的日志收集器，注册为 flog.Get 可以获取的共享日志收集器，并替换默认日志收集器
+// 被替换的日志收集器不会被关闭
+func (r *Config) Apply() (map[string]*flog.Logger, error) {
+	loggers, err := r.Build()
+	if err != nil {
+		return nil, err
+	}
+	for channel, logger := range loggers {
+		flog.Register(channel, logger)
+	}
+	if r.Default != "" {
+		flog.SetDefault(loggers[r.Default])
+	}
+	return loggers, nil
+}
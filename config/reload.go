@@ -0,0 +1,186 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"github.com/buexplain/go-flog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Reloader 热加载配置文件，收到信号或者配置文件发生变化时，重建正在运行的日志收集器的日志处理器与额外日志信息处理器
+// 日志收集器本身保持不变，持有日志收集器的代码无需更新引用，日志收集器的异步队列容量只在创建时生效
+// 新的日志处理器生效后，旧的日志处理器才会处理完队列中的日志并关闭，热加载期间的日志不会丢失
+// 配置文件中删除的渠道，其日志收集器保持原样继续运行
+// 写入同一个文件的新旧文件日志处理器在替换期间短暂并存，两者都以追加方式写入，不会互相覆盖
+// 但旧的日志处理器关闭前冲刷的缓冲可能排在新的日志处理器写入的日志之后
+type Reloader struct {
+	path string
+	lock *sync.Mutex
+	//已经创建的各个渠道的日志收集器
+	loggers map[string]*flog.Logger
+	//最近一次加载时配置文件的修改时间与大小
+	modTime time.Time
+	size    int64
+	//监听过程中重新加载失败的处理函数
+	onError func(err error)
+	closed  chan struct{}
+	once    *sync.Once
+	wg      *sync.WaitGroup
+}
+
+// NewReloader 加载配置文件，创建并注册各个渠道的日志收集器，之后可以调用 Reload 或者开启监听重新加载
+func NewReloader(path string) (*Reloader, error) {
+	tmp := new(Reloader)
+	tmp.path = path
+	tmp.lock = new(sync.Mutex)
+	tmp.loggers = map[string]*flog.Logger{}
+	tmp.onError = func(err error) {
+		flog.Default().Error("flog: reload " + path + " error: " + err.Error())
+	}
+	tmp.closed = make(chan struct{})
+	tmp.once = new(sync.Once)
+	tmp.wg = new(sync.WaitGroup)
+	if err := tmp.Reload(); err != nil {
+		return nil, err
+	}
+	return tmp, nil
+}
+
+// OnError 设置监听过程中重新加载失败的处理函数，默认写入默认日志收集器，需要在开启监听之前调用
+func (r *Reloader) OnError(fn func(err error)) *Reloader {
+	if fn != nil {
+		r.onError = fn
+	}
+	return r
+}
+
+// Loggers 返回各个渠道的日志收集器
+func (r *Reloader) Loggers() map[string]*flog.Logger {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	tmp := make(map[string]*flog.Logger, len(r.loggers))
+	for k, v := range r.loggers {
+		tmp[k] = v
+	}
+	return tmp
+}
+
+// Reload 重新加载配置文件，配置有误时保留当前的日志处理器并返回错误
+// 已有渠道原子替换日志处理器与额外日志信息处理器，新增渠道创建并注册日志收集器
+func (r *Reloader) Reload() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return err
+	}
+	r.modTime = info.ModTime()
+	r.size = info.Size()
+	cfg, err := ParseFile(r.path)
+	if err != nil {
+		return err
+	}
+	built, err := cfg.build()
+	if err != nil {
+		return err
+	}
+	bag := bytes.Buffer{}
+	for i, v := range cfg.Loggers {
+		c := built[i]
+		logger, ok := r.loggers[v.Channel]
+		if !ok {
+			logger = newLogger(v, c)
+			r.loggers[v.Channel] = logger
+			flog.Register(v.Channel, logger)
+			continue
+		}
+		if e := logger.Swap(c.handlers, c.extras, c.contextExtras); e != nil {
+			if errors.Is(e, flog.ErrSwapClosed) {
+				//新的日志处理器没有生效，关闭它们，避免泄露文件句柄与写入go程
+				c.close()
+			}
+			bag.WriteString(v.Channel + ": " + e.Error())
+			bag.WriteByte('\n')
+		}
+	}
+	if cfg.Default != "" {
+		flog.SetDefault(r.loggers[cfg.Default])
+	}
+	if bag.Len() > 0 {
+		return errors.New(bag.String())
+	}
+	return nil
+}
+
+func (r *Reloader) reload() {
+	if err := r.Reload(); err != nil {
+		r.onError(err)
+	}
+}
+
+// WatchSignal 收到信号时重新加载配置文件，默认监听 SIGHUP
+func (r *Reloader) WatchSignal(sig ...os.Signal) *Reloader {
+	if len(sig) == 0 {
+		sig = append(sig, syscall.SIGHUP)
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sig...)
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ch:
+				r.reload()
+			case <-r.closed:
+				return
+			}
+		}
+	}()
+	return r
+}
+
+// WatchFile 每隔 interval 检查配置文件的修改时间与大小，发生变化时重新加载配置文件
+func (r *Reloader) WatchFile(interval time.Duration) *Reloader {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if r.changed() {
+					r.reload()
+				}
+			case <-r.closed:
+				return
+			}
+		}
+	}()
+	return r
+}
+
+// 判断配置文件是否发生变化，文件暂时不存在时视为没有变化，比如编辑器先删除再写入
+func (r *Reloader) changed() bool {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return false
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return !info.ModTime().Equal(r.modTime) || info.Size() != r.size
+}
+
+// Close 停止监听，日志收集器不会被关闭，需要调用 flog.CloseAll 或者各个日志收集器的 Close
+func (r *Reloader) Close() {
+	r.once.Do(func() {
+		close(r.closed)
+	})
+	r.wg.Wait()
+}
@@ -0,0 +1,160 @@
+package config_test
+
+import (
+	"fmt"
+	"github.com/buexplain/go-flog"
+	"github.com/buexplain/go-flog/config"
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/handler"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// 写入只有一个 file 日志处理器的配置文件
+func writeReloadConfig(t *testing.T, file string, dir string, level string) {
+	data := fmt.Sprintf(`{"loggers":[{"channel":"reload","handlers":[{"type":"file","level":%q,"options":{"path":%q}}]}]}`, level, filepath.ToSlash(dir))
+	if err := os.WriteFile(file, []byte(data), 0666); err != nil {
+		t.Fatal("写入配置文件失败", err)
+	}
+}
+
+// 返回 reload 渠道的 file 日志处理器
+func reloadHandler(reloader *config.Reloader) *handler.File {
+	return reloader.Loggers()["reload"].GetHandlers()[0].(*handler.File)
+}
+
+func TestReloader(t *testing.T) {
+	root, err := os.MkdirTemp("", "flog-reload")
+	if err != nil {
+		t.Fatal("构建临时目录失败", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(root)
+	}()
+	file := filepath.Join(root, "flog.json")
+	writeReloadConfig(t, file, filepath.Join(root, "a"), "info")
+	reloader, err := config.NewReloader(file)
+	if err != nil {
+		t.Fatal("加载配置文件失败", err)
+	}
+	defer reloader.Close()
+	logger := flog.Get("reload")
+	defer func() {
+		flog.Register("reload", nil)
+		_ = logger.Close()
+	}()
+	if reloader.Loggers()["reload"] != logger {
+		t.Error("没有注册共享日志收集器")
+	}
+	logger.Info("before")
+	//修改日志目录与日志等级后手动重新加载，日志收集器保持不变
+	writeReloadConfig(t, file, filepath.Join(root, "b"), "debug")
+	if err := reloader.Reload(); err != nil {
+		t.Fatal("重新加载配置文件失败", err)
+	}
+	h := reloadHandler(reloader)
+	if reloader.Loggers()["reload"] != logger || h.GetLevel() != contract.LevelDebug || filepath.Base(h.GetPath()) != "b" {
+		t.Error("重新加载后日志处理器错误", h.GetPath(), h.GetLevel())
+	}
+	logger.Debug("after")
+	a, _ := filepath.Glob(filepath.Join(root, "a", "*.log"))
+	b, _ := filepath.Glob(filepath.Join(root, "b", "*.log"))
+	if len(a) != 1 || len(b) != 1 {
+		t.Error("重新加载前后的日志没有写入各自的目录", a, b)
+	}
+	//配置有误时保留当前的日志处理器
+	if err := os.WriteFile(file, []byte(`{"loggers":[{"channel":"reload","handlers":[{"type":"unknown"}]}]}`), 0666); err != nil {
+		t.Fatal("写入配置文件失败", err)
+	}
+	if err := reloader.Reload(); err == nil || reloadHandler(reloader) != h {
+		t.Error("配置有误时应该返回错误并保留当前的日志处理器", err)
+	}
+	//监听信号
+	errs := make(chan error, 10)
+	reloader.OnError(func(err error) {
+		errs <- err
+	})
+	if runtime.GOOS != "windows" {
+		reloader.WatchSignal(syscall.SIGHUP)
+		writeReloadConfig(t, file, filepath.Join(root, "d"), "warning")
+		process, _ := os.FindProcess(os.Getpid())
+		if err := process.Signal(syscall.SIGHUP); err != nil {
+			t.Fatal("发送信号失败", err)
+		}
+		waitReload(reloader, contract.LevelWarning)
+		if h := reloadHandler(reloader); h.GetLevel() != contract.LevelWarning || filepath.Base(h.GetPath()) != "d" {
+			t.Error("收到信号后没有重新加载", h.GetPath(), h.GetLevel())
+		}
+	}
+	//监听配置文件的变化
+	reloader.WatchFile(10 * time.Millisecond)
+	writeReloadConfig(t, file, filepath.Join(root, "c"), "error")
+	waitReload(reloader, contract.LevelError)
+	if h := reloadHandler(reloader); h.GetLevel() != contract.LevelError || filepath.Base(h.GetPath()) != "c" {
+		t.Error("配置文件变化后没有重新加载", h.GetPath(), h.GetLevel())
+	}
+	select {
+	case err := <-errs:
+		t.Error("监听过程中重新加载失败", err)
+	default:
+	}
+}
+
+// 等待重新加载后的日志等级生效
+func waitReload(reloader *config.Reloader, level contract.Level) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) && reloadHandler(reloader).GetLevel() != level {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// 记录关闭次数的日志处理器
+type closingHandler struct {
+	closed *int32
+}
+
+func (r closingHandler) Handle(record *contract.Record) bool {
+	return false
+}
+
+func (r closingHandler) IsHandling(level contract.Level) bool {
+	return true
+}
+
+func (r closingHandler) Close() error {
+	atomic.AddInt32(r.closed, 1)
+	return nil
+}
+
+func TestReloaderSwapClosed(t *testing.T) {
+	var closed int32
+	config.RegisterHandler("closing", func(cfg config.HandlerConfig) (contract.Handler, error) {
+		return closingHandler{closed: &closed}, nil
+	})
+	file := filepath.Join(t.TempDir(), "flog.json")
+	if err := os.WriteFile(file, []byte(`{"loggers":[{"channel":"closing","handlers":[{"type":"closing"}]}]}`), 0666); err != nil {
+		t.Fatal("写入配置文件失败", err)
+	}
+	reloader, err := config.NewReloader(file)
+	if err != nil {
+		t.Fatal("加载配置文件失败", err)
+	}
+	defer reloader.Close()
+	defer flog.Register("closing", nil)
+	_ = reloader.Loggers()["closing"].Close()
+	if n := atomic.LoadInt32(&closed); n != 1 {
+		t.Fatalf("期待关闭 1 次，实际关闭 %d 次", n)
+	}
+	//日志收集器已经关闭，新建的日志处理器没有生效，需要被关闭
+	if err := reloader.Reload(); err == nil {
+		t.Error("日志收集器已经关闭时重新加载应该返回错误")
+	}
+	if n := atomic.LoadInt32(&closed); n != 2 {
+		t.Errorf("没有生效的日志处理器没有被关闭，关闭 %d 次", n)
+	}
+}
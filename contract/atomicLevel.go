@@ -0,0 +1,31 @@
+package contract
+
+import "sync/atomic"
+
+// AtomicLevel 可以在运行时安全修改的日志等级，可以在多个日志处理器之间共享
+type AtomicLevel struct {
+	level int32
+}
+
+func NewAtomicLevel(level Level) *AtomicLevel {
+	return &AtomicLevel{level: int32(level)}
+}
+
+func (r *AtomicLevel) SetLevel(level Level) {
+	atomic.StoreInt32(&r.level, int32(level))
+}
+
+func (r *AtomicLevel) GetLevel() Level {
+	return Level(atomic.LoadInt32(&r.level))
+}
+
+// IsHandling 判断日志等级是否达到当前等级
+func (r *AtomicLevel) IsHandling(level Level) bool {
+	return level <= r.GetLevel()
+}
+
+// Leveler 可以在运行时调整日志等级的接口
+type Leveler interface {
+	SetLevel(level Level)
+	GetLevel() Level
+}
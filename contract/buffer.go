@@ -0,0 +1,49 @@
+package contract
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// 回收的缓冲区容量超过该值时不再复用，避免个别超长日志让对象池长期持有大块内存
+const maxPooledBuffer = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// AcquireBuffer 从对象池获取一个空的缓冲区
+func AcquireBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+// ReleaseBuffer 将缓冲区归还对象池，归还后调用方不能再使用该缓冲区
+func ReleaseBuffer(buf *bytes.Buffer) {
+	if buf == nil || buf.Cap() > maxPooledBuffer {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// 关闭时归还缓冲区的读取器
+type bufferReader struct {
+	*bytes.Buffer
+	closed int32
+}
+
+// NewBufferReader 将缓冲区包装为 io.ReadCloser，比如作为http请求体，关闭时缓冲区归还对象池
+func NewBufferReader(buf *bytes.Buffer) io.ReadCloser {
+	return &bufferReader{Buffer: buf}
+}
+
+func (r *bufferReader) Close() error {
+	if atomic.CompareAndSwapInt32(&r.closed, 0, 1) {
+		ReleaseBuffer(r.Buffer)
+	}
+	return nil
+}
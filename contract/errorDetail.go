@@ -0,0 +1,136 @@
+package contract
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// 错误链的最大深度，避免自引用的错误无限展开
+const maxErrorDepth = 16
+
+// ErrorDetail 结构化的错误，收集日志时由上下文、结构化字段与附加信息中的 error 转换而来
+// 包含错误信息、错误类型、errors.Unwrap 与 errors.Join 展开的错误链以及可选的调用栈
+type ErrorDetail struct {
+	//错误信息
+	Message string
+	//错误类型
+	Type string
+	//被包装的错误，errors.Join 或者 Unwrap() []error 展开为多个
+	Causes []*ErrorDetail
+	//收集日志时的调用栈，只有最外层的错误才有
+	Stack string
+	//原始错误
+	err error
+}
+
+// NewErrorDetail 将 error 转换为结构化的错误，err 已经是 *ErrorDetail 时只补充调用栈
+// err 是值为 nil 的指针等类型化的 nil 时，错误信息为 <nil>，不会调用其 Error 方法
+func NewErrorDetail(err error, stack string) *ErrorDetail {
+	if detail, ok := err.(*ErrorDetail); ok && detail != nil {
+		if stack == "" || detail.Stack != "" {
+			return detail
+		}
+		tmp := *detail
+		tmp.Stack = stack
+		return &tmp
+	}
+	tmp := newErrorDetail(err, 0)
+	tmp.Stack = stack
+	return tmp
+}
+
+func newErrorDetail(err error, depth int) *ErrorDetail {
+	if isNil(err) {
+		//类型化的 nil 调用 Error 或者 Unwrap 方法可能 panic
+		return &ErrorDetail{Message: "<nil>", Type: fmt.Sprintf("%T", err), err: err}
+	}
+	tmp := &ErrorDetail{Message: err.Error(), Type: fmt.Sprintf("%T", err), err: err}
+	if depth >= maxErrorDepth {
+		return tmp
+	}
+	switch x := err.(type) {
+	case interface{ Unwrap() []error }:
+		for _, cause := range x.Unwrap() {
+			if cause != nil {
+				tmp.Causes = append(tmp.Causes, newErrorDetail(cause, depth+1))
+			}
+		}
+	case interface{ Unwrap() error }:
+		if cause := x.Unwrap(); cause != nil {
+			tmp.Causes = append(tmp.Causes, newErrorDetail(cause, depth+1))
+		}
+	}
+	return tmp
+}
+
+// 判断 error 是否是 nil 或者类型化的 nil
+func isNil(err error) bool {
+	if err == nil {
+		return true
+	}
+	v := reflect.ValueOf(err)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return v.IsNil()
+	default:
+		return false
+	}
+}
+
+func (r *ErrorDetail) Error() string {
+	return r.Message
+}
+
+// Unwrap 返回原始错误，保证 errors.Is 与 errors.As 在日志处理器中仍然可用
+func (r *ErrorDetail) Unwrap() error {
+	return r.err
+}
+
+// Format 实现 fmt.Formatter，%v 与 %s 只输出错误信息
+// %+v 在一行内输出错误类型与错误链，比如：read config: EOF (*fmt.wrapError) <- EOF (*errors.errorString)，有调用栈时换行追加调用栈
+func (r *ErrorDetail) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			b := &strings.Builder{}
+			r.appendText(b)
+			if r.Stack != "" {
+				b.WriteByte('\n')
+				b.WriteString(strings.TrimRight(r.Stack, "\n"))
+			}
+			_, _ = io.WriteString(s, b.String())
+			return
+		}
+		_, _ = io.WriteString(s, r.Message)
+	case 's':
+		_, _ = io.WriteString(s, r.Message)
+	case 'q':
+		_, _ = fmt.Fprintf(s, "%q", r.Message)
+	default:
+		_, _ = fmt.Fprintf(s, "%%!%c(%s)", verb, r.Message)
+	}
+}
+
+func (r *ErrorDetail) appendText(b *strings.Builder) {
+	b.WriteString(r.Message)
+	b.WriteString(" (")
+	b.WriteString(r.Type)
+	b.WriteByte(')')
+	switch len(r.Causes) {
+	case 0:
+	case 1:
+		b.WriteString(" <- ")
+		r.Causes[0].appendText(b)
+	default:
+		b.WriteString(" <- [")
+		for i, cause := range r.Causes {
+			if i > 0 {
+				b.WriteString("; ")
+			}
+			cause.appendText(b)
+		}
+		b.WriteByte(']')
+	}
+}
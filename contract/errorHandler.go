@@ -0,0 +1,104 @@
+package contract
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrorKind 日志库内部错误的类型
+type ErrorKind uint8
+
+const (
+	// ErrorKindPanic 日志处理器恐慌
+	ErrorKindPanic ErrorKind = iota
+	// ErrorKindFormat 格式化日志失败
+	ErrorKindFormat
+	// ErrorKindWrite 写入日志失败
+	ErrorKindWrite
+	// ErrorKindOpen 打开或切换日志文件失败
+	ErrorKindOpen
+	// ErrorKindFlush 冲刷缓冲区失败
+	ErrorKindFlush
+	// ErrorKindRequest 发送日志请求失败
+	ErrorKindRequest
+	// ErrorKindTimeout 发送日志请求超时
+	ErrorKindTimeout
+)
+
+var errorKindToName = [...]string{
+	ErrorKindPanic:   "panic",
+	ErrorKindFormat:  "format",
+	ErrorKindWrite:   "write",
+	ErrorKindOpen:    "open",
+	ErrorKindFlush:   "flush",
+	ErrorKindRequest: "request",
+	ErrorKindTimeout: "timeout",
+}
+
+func (r ErrorKind) String() string {
+	if int(r) < len(errorKindToName) {
+		return errorKindToName[r]
+	}
+	return "unknown"
+}
+
+// Error 日志库内部的结构化错误
+type Error struct {
+	//出错的日志处理器名称
+	Handler string
+	//错误类型
+	Kind ErrorKind
+	//出错时正在处理的日志，与日志无关的错误为nil
+	//错误处理器返回后日志可能被回收复用，需要继续持有日志时必须先调用 Record.Retain
+	Record *Record
+	//原始错误，恐慌时为包装了恐慌值的错误
+	Err error
+}
+
+func (r *Error) Error() string {
+	return fmt.Sprintf("flog: handler %s %s error: %v", r.Handler, r.Kind, r.Err)
+}
+
+func (r *Error) Unwrap() error {
+	return r.Err
+}
+
+// ErrorHandler 日志库内部错误的处理器，可以统计、告警或者转发日志库自身的错误
+// 错误处理器中写入日志时，应该写入与出错的日志处理器无关的日志收集器，避免错误循环产生
+type ErrorHandler interface {
+	HandleError(err *Error)
+}
+
+// ErrorHandlerFunc 函数形式的错误处理器
+type ErrorHandlerFunc func(err *Error)
+
+func (r ErrorHandlerFunc) HandleError(err *Error) {
+	r(err)
+}
+
+// AtomicErrorHandler 可以在运行时安全替换的错误处理器
+type AtomicErrorHandler struct {
+	handler atomic.Value
+}
+
+// 保证 atomic.Value 中存放的类型一致
+type errorHandlerBox struct {
+	handler ErrorHandler
+}
+
+func (r *AtomicErrorHandler) SetErrorHandler(handler ErrorHandler) {
+	r.handler.Store(errorHandlerBox{handler: handler})
+}
+
+func (r *AtomicErrorHandler) GetErrorHandler() ErrorHandler {
+	if box, ok := r.handler.Load().(errorHandlerBox); ok {
+		return box.handler
+	}
+	return nil
+}
+
+// ErrorHandlerSetter 可以设置错误处理器的接口
+type ErrorHandlerSetter interface {
+	SetErrorHandler(handler ErrorHandler)
+	GetErrorHandler() ErrorHandler
+}
@@ -1,6 +1,13 @@
 package contract
 
+import "context"
+
 //添加日志额外信息的接口
 type Extra interface {
 	Processor(record *Record)
 }
+
+//从 context.Context 中提取信息并添加到日志额外信息的接口
+type ContextExtra interface {
+	ContextProcessor(ctx context.Context, record *Record)
+}
@@ -0,0 +1,72 @@
+package contract
+
+import (
+	"math"
+	"time"
+)
+
+// FieldType 结构化字段的类型
+type FieldType uint8
+
+const (
+	// FieldTypeUnknown 未知类型，值为 nil
+	FieldTypeUnknown FieldType = iota
+	// FieldTypeString 字符串，值存放于 String
+	FieldTypeString
+	// FieldTypeInt64 有符号整数，值存放于 Integer
+	FieldTypeInt64
+	// FieldTypeUint64 无符号整数，值按位存放于 Integer
+	FieldTypeUint64
+	// FieldTypeFloat64 浮点数，值按位存放于 Integer
+	FieldTypeFloat64
+	// FieldTypeBool 布尔值，值存放于 Integer，1 为真
+	FieldTypeBool
+	// FieldTypeDuration 时间间隔，值存放于 Integer
+	FieldTypeDuration
+	// FieldTypeTime 时间，值存放于 Interface，零值时间与超出纳秒时间戳范围的时间也能完整保存
+	FieldTypeTime
+	// FieldTypeError 错误，值存放于 Interface
+	FieldTypeError
+	// FieldTypeAny 任意值，值存放于 Interface，格式化时退化为反射
+	FieldTypeAny
+)
+
+// Field 结构化字段
+// 按类型保存值，避免装箱，格式化时无需反射
+type Field struct {
+	Key       string
+	Type      FieldType
+	Integer   int64
+	String    string
+	Interface interface{}
+}
+
+// Value 返回字段的值，供无法直接识别字段类型的格式化处理器使用
+func (r Field) Value() interface{} {
+	switch r.Type {
+	case FieldTypeString:
+		return r.String
+	case FieldTypeInt64:
+		return r.Integer
+	case FieldTypeUint64:
+		return uint64(r.Integer)
+	case FieldTypeFloat64:
+		return math.Float64frombits(uint64(r.Integer))
+	case FieldTypeBool:
+		return r.Integer == 1
+	case FieldTypeDuration:
+		return time.Duration(r.Integer)
+	case FieldTypeTime:
+		return r.Time()
+	case FieldTypeError, FieldTypeAny:
+		return r.Interface
+	default:
+		return nil
+	}
+}
+
+// Time 返回 FieldTypeTime 类型字段的时间
+func (r Field) Time() time.Time {
+	t, _ := r.Interface.(time.Time)
+	return t
+}
@@ -0,0 +1,13 @@
+package contract
+
+// Filter 日志过滤器接口，返回false的日志会被丢弃
+type Filter interface {
+	Filter(record *Record) bool
+}
+
+// FilterFunc 函数形式的日志过滤器
+type FilterFunc func(record *Record) bool
+
+func (r FilterFunc) Filter(record *Record) bool {
+	return r(record)
+}
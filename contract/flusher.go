@@ -0,0 +1,9 @@
+package contract
+
+import "context"
+
+// Flusher 日志冲刷接口，日志处理器可选实现
+type Flusher interface {
+	//将缓冲中的日志写入目的地，阻塞直到写入完成或者ctx结束
+	Flush(ctx context.Context) error
+}
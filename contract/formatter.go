@@ -5,8 +5,9 @@ import (
 	"io"
 )
 
-//日志格式化接口
+// 日志格式化接口
 type Formatter interface {
 	ToWriter(w io.Writer, record *Record) (written int64, err error)
-    ToBuffer(record *Record) (buf *bytes.Buffer, err error)
+	//返回的缓冲区归调用方所有，用完后可以调用 ReleaseBuffer 归还对象池
+	ToBuffer(record *Record) (buf *bytes.Buffer, err error)
 }
@@ -2,10 +2,17 @@ package contract
 
 //日志处理器接口
 type Handler interface {
-	//处理器入口
+	//处理器入口，Handle 返回后日志可能被回收复用，需要继续持有日志的处理器必须先调用 record.Retain
 	Handle(record *Record) bool
 	//判断当前处理器是否可以处理日志
 	IsHandling(level Level) bool
 	//关闭日志处理器
 	Close() error
 }
+
+// TryHandler 可以同步返回写入错误的日志处理器，故障转移等需要判断写入是否成功的包装器依赖该接口
+type TryHandler interface {
+	Handler
+	//与 Handle 相同，但写入失败时不交给错误处理器，而是返回结构化的错误，由调用方处理
+	TryHandle(record *Record) (bool, *Error)
+}
@@ -1,5 +1,7 @@
 package contract
 
+import "strings"
+
 // Level 日志等级类型
 type Level int
 
@@ -73,3 +75,9 @@ func GetNameByLevel(level Level) (name string) {
 	}
 	return
 }
+
+// ParseLevel 解析日志等级名称，名称不区分大小写
+func ParseLevel(name string) (level Level, ok bool) {
+	level, ok = nameToLevel[strings.ToLower(strings.TrimSpace(name))]
+	return
+}
@@ -1,5 +1,7 @@
 package contract
 
+import "context"
+
 /**
  * 日志等级接口
  * @see https://tools.ietf.org/html/rfc5424
@@ -52,4 +54,10 @@ type Logger interface {
 	 */
 	Debug(message string, context ...interface{})
 	DebugF(format string, v ...interface{})
+
+	/**
+	 * 携带 context.Context 的日志入口，context.Context 会交给 ContextExtra 提取信息
+	 */
+	Log(ctx context.Context, level Level, message string, context ...interface{})
+	LogF(ctx context.Context, level Level, format string, v ...interface{})
 }
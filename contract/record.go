@@ -1,6 +1,10 @@
 package contract
 
-import "time"
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
 
 //日志信息结构体
 type Record struct {
@@ -12,12 +16,95 @@ type Record struct {
 	Message string
 	//上下文
 	Context interface{}
+	//结构化字段
+	Fields []Field
 	//附加信息
 	Extra map[string]interface{}
 	//时间
 	Time time.Time
+	//引用计数，只有从对象池获取的日志才会被回收
+	refs int32
+	//是否从对象池获取
+	pooled bool
+	//处理时产生的内部错误是否禁止回流到日志处理器
+	noReflux bool
 }
 
 func NewRecord() *Record {
 	return &Record{Extra: make(map[string]interface{}), Time: time.Now()}
 }
+
+// 回收的日志的附加信息超过该数量时不再复用，避免对象池长期持有大对象
+const maxPooledExtra = 64
+
+var recordPool = sync.Pool{
+	New: func() interface{} {
+		return &Record{Extra: make(map[string]interface{})}
+	},
+}
+
+// AcquireRecord 从对象池获取日志，引用计数为1，所有持有者用完后调用 Release 归还对象池
+func AcquireRecord() *Record {
+	tmp := recordPool.Get().(*Record)
+	tmp.refs = 1
+	tmp.pooled = true
+	tmp.Time = time.Now()
+	return tmp
+}
+
+// Retain 增加引用计数
+// 日志处理器在 Handle 返回后仍需持有日志时，比如抛入自己的队列，必须先调用本方法，持有结束后再调用 Release
+func (r *Record) Retain() *Record {
+	if r.pooled {
+		atomic.AddInt32(&r.refs, 1)
+	}
+	return r
+}
+
+// Release 减少引用计数，计数归零时重置日志并归还对象池，NewRecord 创建的日志不会被回收
+func (r *Record) Release() {
+	if !r.pooled {
+		return
+	}
+	n := atomic.AddInt32(&r.refs, -1)
+	if n > 0 {
+		return
+	}
+	if n < 0 {
+		panic("contract: record released more times than retained")
+	}
+	r.reset()
+	recordPool.Put(r)
+}
+
+// DisableReflux 禁止处理本日志时产生的内部错误经重定向的标准库 log 回流到日志处理器，内部错误直接写入标准错误
+// 通过重定向写入的日志会被标记，避免内部错误递归
+func (r *Record) DisableReflux() {
+	r.noReflux = true
+}
+
+// RefluxDisabled 判断处理本日志时产生的内部错误是否禁止回流
+func (r *Record) RefluxDisabled() bool {
+	return r.noReflux
+}
+
+// 重置日志，清除所有引用，保留切片与map的容量
+func (r *Record) reset() {
+	r.Channel = ""
+	r.Level = ""
+	r.Message = ""
+	r.Context = nil
+	for i := range r.Fields {
+		r.Fields[i] = Field{}
+	}
+	r.Fields = r.Fields[:0]
+	if len(r.Extra) > maxPooledExtra {
+		r.Extra = make(map[string]interface{})
+	} else {
+		for k := range r.Extra {
+			delete(r.Extra, k)
+		}
+	}
+	r.Time = time.Time{}
+	r.noReflux = false
+}
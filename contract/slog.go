@@ -0,0 +1,59 @@
+package contract
+
+import "log/slog"
+
+// log/slog 只定义了 Debug、Info、Warn、Error 四个等级，其余 RFC 5424 等级按以下数值映射
+const (
+	// SlogLevelNotice 注意，介于 slog.LevelInfo 与 slog.LevelWarn 之间
+	SlogLevelNotice = slog.LevelInfo + 2
+	// SlogLevelCritical 严重
+	SlogLevelCritical = slog.LevelError + 4
+	// SlogLevelAlert 警报
+	SlogLevelAlert = slog.LevelError + 8
+	// SlogLevelEmergency 紧急情况
+	SlogLevelEmergency = slog.LevelError + 12
+)
+
+// ToSlogLevel 将日志等级转换为 slog.Level
+func ToSlogLevel(level Level) slog.Level {
+	switch level {
+	case LevelEmergency:
+		return SlogLevelEmergency
+	case LevelAlert:
+		return SlogLevelAlert
+	case LevelCritical:
+		return SlogLevelCritical
+	case LevelError:
+		return slog.LevelError
+	case LevelWarning:
+		return slog.LevelWarn
+	case LevelNotice:
+		return SlogLevelNotice
+	case LevelInfo:
+		return slog.LevelInfo
+	default:
+		return slog.LevelDebug
+	}
+}
+
+// FromSlogLevel 将 slog.Level 转换为日志等级，自定义的 slog.Level 归入不高于它的最近一个等级
+func FromSlogLevel(level slog.Level) Level {
+	switch {
+	case level >= SlogLevelEmergency:
+		return LevelEmergency
+	case level >= SlogLevelAlert:
+		return LevelAlert
+	case level >= SlogLevelCritical:
+		return LevelCritical
+	case level >= slog.LevelError:
+		return LevelError
+	case level >= slog.LevelWarn:
+		return LevelWarning
+	case level >= SlogLevelNotice:
+		return LevelNotice
+	case level >= slog.LevelInfo:
+		return LevelInfo
+	default:
+		return LevelDebug
+	}
+}
@@ -0,0 +1,6 @@
+package contract
+
+// LogValuer 延迟求值的日志值，只有确认有日志处理器处理该等级的日志时才会求值，求值结果仍然是 LogValuer 时继续求值
+type LogValuer interface {
+	LogValue() interface{}
+}
@@ -0,0 +1,107 @@
+package flog
+
+import (
+	"github.com/buexplain/go-flog/contract"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// 调用栈的最大帧数
+const maxStackFrames = 64
+
+// SetStackLevel 设置捕获调用栈的日志等级，等于或严重于该等级的日志中的错误会附带收集日志时的调用栈
+// 默认为 -1，不捕获调用栈
+func (r *Logger) SetStackLevel(level contract.Level) {
+	atomic.StoreInt32(&r.root.stackLevel, int32(level))
+}
+
+func (r *Logger) GetStackLevel() contract.Level {
+	return contract.Level(atomic.LoadInt32(&r.root.stackLevel))
+}
+
+// 判断日志中是否有需要转换的 error
+func hasErrors(record *contract.Record) bool {
+	switch context := record.Context.(type) {
+	case error:
+		return true
+	case []interface{}:
+		for _, v := range context {
+			if _, ok := v.(error); ok {
+				return true
+			}
+		}
+	}
+	for _, field := range record.Fields {
+		if field.Type == contract.FieldTypeError {
+			return true
+		}
+	}
+	for _, v := range record.Extra {
+		if _, ok := v.(error); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// 将日志的上下文、结构化字段与附加信息中的 error 转换为 *contract.ErrorDetail
+func convertErrors(record *contract.Record, stack string) {
+	switch context := record.Context.(type) {
+	case error:
+		record.Context = contract.NewErrorDetail(context, stack)
+	case []interface{}:
+		//上下文可能是调用方传入的切片，复制后再修改
+		var tmp []interface{}
+		for i, v := range context {
+			if err, ok := v.(error); ok {
+				if tmp == nil {
+					tmp = append(make([]interface{}, 0, len(context)), context...)
+				}
+				tmp[i] = contract.NewErrorDetail(err, stack)
+			}
+		}
+		if tmp != nil {
+			record.Context = tmp
+		}
+	}
+	for i, field := range record.Fields {
+		if err, ok := field.Interface.(error); ok && field.Type == contract.FieldTypeError {
+			record.Fields[i].Interface = contract.NewErrorDetail(err, stack)
+		}
+	}
+	for k, v := range record.Extra {
+		if err, ok := v.(error); ok {
+			record.Extra[k] = contract.NewErrorDetail(err, stack)
+		}
+	}
+}
+
+// 捕获调用栈，skip 是 captureStack 的调用方之上需要跳过的栈帧数，跳过后从业务代码调用日志方法的位置开始
+// 开头属于 slog 包的栈帧也会被跳过
+func captureStack(skip int) string {
+	var pcs [maxStackFrames]uintptr
+	//跳过 runtime.Callers、captureStack 与其调用方
+	n := runtime.Callers(skip+3, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	b := &strings.Builder{}
+	leading := true
+	for {
+		frame, more := frames.Next()
+		if leading && strings.HasPrefix(frame.Function, "log/slog.") && more {
+			continue
+		}
+		leading = false
+		b.WriteString(frame.Function)
+		b.WriteString("\n\t")
+		b.WriteString(frame.File)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(frame.Line))
+		b.WriteByte('\n')
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,132 @@
+package flog_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/buexplain/go-flog"
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/formatter"
+	"strings"
+	"testing"
+)
+
+func TestLoggerErrorDetail(t *testing.T) {
+	memory := newMemoryHandler(contract.LevelDebug)
+	logger := flog.New("error", memory)
+	logger.SetStackLevel(contract.LevelError)
+	if logger.GetStackLevel() != contract.LevelError {
+		t.Error("设置捕获调用栈的日志等级失败")
+	}
+	e1 := errors.New("e1")
+	e2 := errors.New("e2")
+	err := fmt.Errorf("wrap: %w", errors.Join(e1, e2))
+	logger.Error("error", err)
+	context := []interface{}{err, 1}
+	logger.With("bound", e1).Info("info", context...)
+	logger.LogFields(nil, contract.LevelCritical, "fields", flog.Err(e2))
+	records := memory.getRecords()
+	if len(records) != 3 {
+		t.Fatalf("期待收集到 3 条日志，实际收集到 %d 条", len(records))
+	}
+	//上下文中的错误转换为结构化的错误，并且捕获调用栈
+	detail, ok := records[0].Context.(*contract.ErrorDetail)
+	if !ok {
+		t.Fatalf("上下文中的错误没有转换为结构化的错误 %T", records[0].Context)
+	}
+	if detail.Type != "*fmt.wrapError" || len(detail.Causes) != 1 || len(detail.Causes[0].Causes) != 2 || detail.Causes[0].Causes[1].Message != "e2" {
+		t.Error("错误链展开错误", detail)
+	}
+	if !errors.Is(detail, e1) || !errors.Is(detail, e2) {
+		t.Error("结构化的错误应该保留原始错误")
+	}
+	if !strings.HasPrefix(detail.Stack, "github.com/buexplain/go-flog_test.TestLoggerErrorDetail\n") || !strings.Contains(detail.Stack, "errorDetail_test.go") {
+		t.Error("调用栈应该从调用日志方法的位置开始", detail.Stack)
+	}
+	//低于捕获调用栈的日志等级时不捕获调用栈，调用方传入的上下文不会被修改
+	items, _ := records[1].Context.([]interface{})
+	if len(items) != 2 || items[0].(*contract.ErrorDetail).Stack != "" || context[0] != err {
+		t.Error("上下文切片中的错误转换错误", records[1].Context, context)
+	}
+	if bound, ok := records[1].Extra["bound"].(*contract.ErrorDetail); !ok || bound.Message != "e1" {
+		t.Error("附加信息中的错误没有转换为结构化的错误", records[1].Extra)
+	}
+	if field, ok := records[2].Fields[0].Interface.(*contract.ErrorDetail); !ok || field.Stack == "" {
+		t.Error("结构化字段中的错误没有转换为结构化的错误", records[2].Fields)
+	}
+	//json 格式化为对象
+	buf, e := formatter.NewJSON().ToBuffer(records[0])
+	if e != nil {
+		t.Fatal("json格式化失败", e)
+	}
+	result := struct {
+		Context struct {
+			Message string
+			Type    string
+			Causes  []struct {
+				Causes []struct{ Message string }
+			}
+			Stack string
+		}
+	}{}
+	if e := json.Unmarshal(buf.Bytes(), &result); e != nil {
+		t.Fatal("json格式化的结果无法解析", e)
+	}
+	if result.Context.Message != "wrap: e1\ne2" || len(result.Context.Causes) != 1 || len(result.Context.Causes[0].Causes) != 2 || result.Context.Stack == "" {
+		t.Error("json格式化的错误不完整", buf.String())
+	}
+	//line 格式化为一行错误链，之后是调用栈
+	buf, _ = formatter.NewLine().ToBuffer(records[1])
+	if !strings.Contains(buf.String(), "wrap: e1\ne2 (*fmt.wrapError) <- e1\ne2 (*errors.joinError) <- [e1 (*errors.errorString); e2 (*errors.errorString)]") {
+		t.Error("line格式化的错误不完整", buf.String())
+	}
+	if fmt.Sprint(detail) != err.Error() {
+		t.Error("默认格式应该只输出错误信息", fmt.Sprint(detail))
+	}
+}
+
+// Error 方法会解引用接收者的错误
+type codeError struct {
+	code int
+}
+
+func (r *codeError) Error() string {
+	return fmt.Sprintf("code %d", r.code)
+}
+
+func TestLoggerTypedNilError(t *testing.T) {
+	memory := newMemoryHandler(contract.LevelDebug)
+	logger := flog.New("error", memory)
+	var err *codeError
+	//类型化的 nil 不应该导致 panic
+	logger.With("cause", error(err)).Error("context", err)
+	logger.Error("slice", err, 1)
+	logger.LogFields(nil, contract.LevelError, "field", flog.Err(err))
+	logger.Error("wrap", fmt.Errorf("wrap: %w", error(err)))
+	records := memory.getRecords()
+	if len(records) != 4 {
+		t.Fatalf("期待收集到 4 条日志，实际收集到 %d 条", len(records))
+	}
+	if detail, ok := records[0].Context.(*contract.ErrorDetail); !ok || detail.Message != "<nil>" || detail.Type != "*flog_test.codeError" {
+		t.Error("上下文中的类型化的 nil 转换错误", records[0].Context)
+	}
+	if detail, ok := records[0].Extra["cause"].(*contract.ErrorDetail); !ok || detail.Message != "<nil>" {
+		t.Error("附加信息中的类型化的 nil 转换错误", records[0].Extra)
+	}
+	if context, ok := records[1].Context.([]interface{}); !ok || context[0].(*contract.ErrorDetail).Message != "<nil>" {
+		t.Error("上下文切片中的类型化的 nil 转换错误", records[1].Context)
+	}
+	if detail, ok := records[2].Fields[0].Interface.(*contract.ErrorDetail); !ok || detail.Message != "<nil>" {
+		t.Error("结构化字段中的类型化的 nil 转换错误", records[2].Fields)
+	}
+	if detail, ok := records[3].Context.(*contract.ErrorDetail); !ok || len(detail.Causes) != 1 || detail.Causes[0].Message != "<nil>" {
+		t.Error("错误链中的类型化的 nil 转换错误", records[3].Context)
+	}
+	var detail *contract.ErrorDetail
+	if contract.NewErrorDetail(detail, "").Message != "<nil>" {
+		t.Error("类型化的 nil 的 *contract.ErrorDetail 转换错误")
+	}
+	if _, e := formatter.NewLine().ToBuffer(records[0]); e != nil {
+		t.Error("line格式化类型化的 nil 失败", e)
+	}
+}
@@ -0,0 +1,26 @@
+package flog
+
+import (
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/internal/report"
+)
+
+// SetErrorHandler 设置内部错误处理器，日志处理器恐慌等日志收集器自身捕获的错误交给该处理器
+// 同时设置给所有实现了 contract.ErrorHandlerSetter 的日志处理器，之后添加的日志处理器需要单独设置
+func (r *Logger) SetErrorHandler(handler contract.ErrorHandler) {
+	r.root.errorHandler.SetErrorHandler(handler)
+	for _, v := range r.GetHandlers() {
+		if setter, ok := v.(contract.ErrorHandlerSetter); ok {
+			setter.SetErrorHandler(handler)
+		}
+	}
+}
+
+func (r *Logger) GetErrorHandler() contract.ErrorHandler {
+	return r.root.errorHandler.GetErrorHandler()
+}
+
+// 处理内部错误
+func (r *Logger) handleError(err *contract.Error) {
+	report.Error(r.root.errorHandler.GetErrorHandler(), err)
+}
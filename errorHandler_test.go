@@ -0,0 +1,78 @@
+package flog_test
+
+import (
+	"github.com/buexplain/go-flog"
+	"github.com/buexplain/go-flog/contract"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 收集内部错误的错误处理器
+type errorCollector struct {
+	lock   *sync.Mutex
+	errors []*contract.Error
+}
+
+func (r *errorCollector) HandleError(err *contract.Error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if err.Record != nil {
+		err.Record.Retain()
+	}
+	r.errors = append(r.errors, err)
+}
+
+func (r *errorCollector) getErrors() []*contract.Error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]*contract.Error(nil), r.errors...)
+}
+
+func TestLoggerErrorHandler(t *testing.T) {
+	for _, async := range []bool{false, true} {
+		collector := &errorCollector{lock: new(sync.Mutex)}
+		logger := flog.New("error", &panicHandler{})
+		logger.SetErrorHandler(collector)
+		if logger.GetErrorHandler() != collector {
+			t.Error("设置错误处理器失败")
+		}
+		if async {
+			logger.Async(10)
+		}
+		logger.Info("boom")
+		_ = logger.Close(10 * time.Millisecond)
+		errs := collector.getErrors()
+		if len(errs) != 1 {
+			t.Fatalf("期待收到 1 个内部错误，实际收到 %d 个", len(errs))
+		}
+		err := errs[0]
+		if err.Kind != contract.ErrorKindPanic || err.Handler != "*flog_test.panicHandler" || err.Record == nil || err.Record.Message != "boom" {
+			t.Errorf("内部错误的信息错误 %+v", err)
+		}
+		if err.Err == nil || err.Err.Error() != "panic: boom" || err.Error() != "flog: handler *flog_test.panicHandler panic error: panic: boom" {
+			t.Error("内部错误的描述错误", err.Error())
+		}
+	}
+	//错误处理器会传递给日志处理器
+	collector := &errorCollector{lock: new(sync.Mutex)}
+	memory := &errorSetterHandler{memoryHandler: newMemoryHandler(contract.LevelDebug)}
+	flog.New("error", memory).SetErrorHandler(contract.ErrorHandlerFunc(collector.HandleError))
+	if memory.GetErrorHandler() == nil {
+		t.Error("错误处理器没有传递给日志处理器")
+	}
+}
+
+// 可以设置错误处理器的日志处理器
+type errorSetterHandler struct {
+	*memoryHandler
+	errorHandler contract.AtomicErrorHandler
+}
+
+func (r *errorSetterHandler) SetErrorHandler(handler contract.ErrorHandler) {
+	r.errorHandler.SetErrorHandler(handler)
+}
+
+func (r *errorSetterHandler) GetErrorHandler() contract.ErrorHandler {
+	return r.errorHandler.GetErrorHandler()
+}
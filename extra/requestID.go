@@ -0,0 +1,38 @@
+package extra
+
+import (
+	"context"
+	"github.com/buexplain/go-flog/contract"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID 将请求id写入 context.Context
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext 从 context.Context 中读取请求id
+func RequestIDFromContext(ctx context.Context) (requestID string, ok bool) {
+	requestID, ok = ctx.Value(requestIDKey{}).(string)
+	return
+}
+
+// RequestID 从 context.Context 中提取请求id
+type RequestID struct {
+	key interface{}
+}
+
+// NewRequestID 默认读取 WithRequestID 写入的请求id，也可以传入业务自己的 context.Context 键
+func NewRequestID(key ...interface{}) *RequestID {
+	if len(key) == 0 {
+		key = append(key, requestIDKey{})
+	}
+	return &RequestID{key: key[0]}
+}
+
+func (r *RequestID) ContextProcessor(ctx context.Context, record *contract.Record) {
+	if v := ctx.Value(r.key); v != nil {
+		record.Extra["RequestID"] = v
+	}
+}
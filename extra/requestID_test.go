@@ -0,0 +1,37 @@
+package extra_test
+
+import (
+	"context"
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/extra"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	ctx := extra.WithRequestID(context.Background(), "req-123")
+	if requestID, ok := extra.RequestIDFromContext(ctx); !ok || requestID != "req-123" {
+		t.Error("读取请求id失败")
+		return
+	}
+	record := &contract.Record{Extra: map[string]interface{}{}}
+	extra.NewRequestID().ContextProcessor(ctx, record)
+	if record.Extra["RequestID"] != "req-123" {
+		t.Error("设置请求id失败", record.Extra)
+		return
+	}
+	//自定义键
+	type customKey struct{}
+	ctx = context.WithValue(context.Background(), customKey{}, "req-456")
+	record = &contract.Record{Extra: map[string]interface{}{}}
+	extra.NewRequestID(customKey{}).ContextProcessor(ctx, record)
+	if record.Extra["RequestID"] != "req-456" {
+		t.Error("通过自定义键设置请求id失败", record.Extra)
+		return
+	}
+	//没有请求id
+	record = &contract.Record{Extra: map[string]interface{}{}}
+	extra.NewRequestID().ContextProcessor(context.Background(), record)
+	if _, ok := record.Extra["RequestID"]; ok {
+		t.Error("没有请求id时不应该设置请求id")
+	}
+}
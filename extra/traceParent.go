@@ -0,0 +1,91 @@
+package extra
+
+import (
+	"context"
+	"github.com/buexplain/go-flog/contract"
+	"strings"
+)
+
+type traceParentKey struct{}
+
+// WithTraceParent 将 W3C traceparent 头写入 context.Context
+func WithTraceParent(ctx context.Context, traceParent string) context.Context {
+	return context.WithValue(ctx, traceParentKey{}, traceParent)
+}
+
+// TraceParentFromContext 从 context.Context 中读取 W3C traceparent 头
+func TraceParentFromContext(ctx context.Context) (traceParent string, ok bool) {
+	traceParent, ok = ctx.Value(traceParentKey{}).(string)
+	return
+}
+
+// TraceParent 从 context.Context 中提取 W3C traceparent 头，并解析出 TraceID、SpanID、TraceFlags
+// @see https://www.w3.org/TR/trace-context/#traceparent-header
+type TraceParent struct {
+	key interface{}
+}
+
+// NewTraceParent 默认读取 WithTraceParent 写入的值，也可以传入业务自己的 context.Context 键
+func NewTraceParent(key ...interface{}) *TraceParent {
+	if len(key) == 0 {
+		key = append(key, traceParentKey{})
+	}
+	return &TraceParent{key: key[0]}
+}
+
+func (r *TraceParent) ContextProcessor(ctx context.Context, record *contract.Record) {
+	traceParent, ok := ctx.Value(r.key).(string)
+	if !ok {
+		return
+	}
+	traceID, spanID, flags, ok := ParseTraceParent(traceParent)
+	if !ok {
+		return
+	}
+	record.Extra["TraceID"] = traceID
+	record.Extra["SpanID"] = spanID
+	record.Extra["TraceFlags"] = flags
+}
+
+// ParseTraceParent 解析 W3C traceparent 头：version-traceid-parentid-traceflags
+func ParseTraceParent(traceParent string) (traceID string, spanID string, flags string, ok bool) {
+	traceParent = strings.TrimSpace(traceParent)
+	//未来的版本允许在末尾追加字段，所以只校验最小长度
+	if len(traceParent) < 55 {
+		return "", "", "", false
+	}
+	parts := strings.SplitN(traceParent, "-", 5)
+	if len(parts) < 4 {
+		return "", "", "", false
+	}
+	version := parts[0]
+	if len(version) != 2 || !isHex(version) || version == "ff" {
+		return "", "", "", false
+	}
+	//00 版本必须恰好四个字段
+	if version == "00" && (len(parts) != 4 || len(traceParent) != 55) {
+		return "", "", "", false
+	}
+	traceID, spanID, flags = parts[1], parts[2], parts[3]
+	if len(traceID) != 32 || !isHex(traceID) || traceID == strings.Repeat("0", 32) {
+		return "", "", "", false
+	}
+	if len(spanID) != 16 || !isHex(spanID) || spanID == strings.Repeat("0", 16) {
+		return "", "", "", false
+	}
+	if len(flags) != 2 || !isHex(flags) {
+		return "", "", "", false
+	}
+	return traceID, spanID, flags, true
+}
+
+// 判断是否为小写十六进制字符串
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
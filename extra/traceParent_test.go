@@ -0,0 +1,37 @@
+package extra_test
+
+import (
+	"context"
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/extra"
+	"testing"
+)
+
+func TestTraceParent(t *testing.T) {
+	traceParent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	ctx := extra.WithTraceParent(context.Background(), traceParent)
+	record := &contract.Record{Extra: map[string]interface{}{}}
+	extra.NewTraceParent().ContextProcessor(ctx, record)
+	if record.Extra["TraceID"] != "4bf92f3577b34da6a3ce929d0e0e4736" || record.Extra["SpanID"] != "00f067aa0ba902b7" || record.Extra["TraceFlags"] != "01" {
+		t.Error("解析traceparent失败", record.Extra)
+		return
+	}
+	invalid := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	}
+	for _, v := range invalid {
+		if _, _, _, ok := extra.ParseTraceParent(v); ok {
+			t.Error("非法的traceparent被解析成功", v)
+		}
+	}
+	//未来的版本允许追加字段
+	if _, _, _, ok := extra.ParseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"); !ok {
+		t.Error("未来版本的traceparent解析失败")
+	}
+}
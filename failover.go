@@ -0,0 +1,255 @@
+package flog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/internal/report"
+	"sync/atomic"
+	"time"
+)
+
+// FailoverHandler 故障转移的日志处理器包装器，按顺序尝试主日志处理器与备用日志处理器，直到有一个写入成功
+// 日志处理器的 Handle 返回值只表示是否进入下一个日志处理器，所以被包装的日志处理器必须实现 contract.TryHandler，同步返回写入错误
+// 写入失败的日志处理器在冷却时间内被跳过，冷却时间结束后用一条日志试探，试探成功则恢复健康
+// 比如先写http接口，失败时写本地文件：NewFailoverHandler(httpHandler, fileHandler)
+// 写入错误交给本包装器的错误处理器，被包装的日志处理器的其它内部错误，比如后台冲刷缓冲区的错误，仍然交给它们自己的错误处理器
+// 异步发送的日志处理器（比如钉钉）无法同步返回写入错误，不能被故障转移
+type FailoverHandler struct {
+	//所有日志处理器都写入失败或者不健康的日志数量，放在结构体开头，保证32位平台上原子操作的内存对齐
+	failed uint64
+	//冷却时间，单位纳秒
+	cooldown int64
+	//按尝试顺序排列的日志处理器
+	sinks []*failoverSink
+	//日志处理器名称
+	name string
+	//内部错误处理器
+	errorHandler contract.AtomicErrorHandler
+}
+
+// 被故障转移的日志处理器及其健康状态
+type failoverSink struct {
+	//不健康状态的结束时间，0表示健康，放在结构体开头，保证32位平台上原子操作的内存对齐
+	until int64
+	//写入失败的次数
+	failures uint64
+	handler  contract.TryHandler
+	parent   *FailoverHandler
+}
+
+// NewFailoverHandler 包装主日志处理器与备用日志处理器，默认冷却时间为30秒
+// 日志处理器没有实现 contract.TryHandler 时返回错误
+func NewFailoverHandler(primary contract.Handler, secondaries ...contract.Handler) (*FailoverHandler, error) {
+	tmp := new(FailoverHandler)
+	tmp.cooldown = int64(30 * time.Second)
+	tmp.name = "failover"
+	for _, handler := range append([]contract.Handler{primary}, secondaries...) {
+		if handler == nil {
+			continue
+		}
+		try, ok := handler.(contract.TryHandler)
+		if !ok {
+			return nil, fmt.Errorf("flog: failover handler %s does not implement contract.TryHandler", report.Name(handler))
+		}
+		tmp.sinks = append(tmp.sinks, &failoverSink{handler: try, parent: tmp})
+	}
+	return tmp, nil
+}
+
+// SetCooldown 设置写入失败的日志处理器的冷却时间，可以在运行时安全调用
+func (r *FailoverHandler) SetCooldown(cooldown time.Duration) *FailoverHandler {
+	if cooldown > 0 {
+		atomic.StoreInt64(&r.cooldown, int64(cooldown))
+	}
+	return r
+}
+
+func (r *FailoverHandler) SetName(name string) *FailoverHandler {
+	r.name = name
+	return r
+}
+
+func (r *FailoverHandler) GetName() string {
+	return r.name
+}
+
+// 标记写入失败，并将错误交给包装器的错误处理器
+func (r *failoverSink) fail(err *contract.Error) {
+	atomic.AddUint64(&r.failures, 1)
+	atomic.StoreInt64(&r.until, time.Now().UnixNano()+atomic.LoadInt64(&r.parent.cooldown))
+	report.Error(r.parent.errorHandler.GetErrorHandler(), err)
+}
+
+// 判断是否可以尝试写入，冷却时间结束后只允许一条日志试探
+func (r *failoverSink) available(now int64) bool {
+	until := atomic.LoadInt64(&r.until)
+	if until == 0 {
+		return true
+	}
+	if now < until {
+		return false
+	}
+	return atomic.CompareAndSwapInt64(&r.until, until, now+atomic.LoadInt64(&r.parent.cooldown))
+}
+
+// 尝试写入日志，返回是否写入成功，以及日志处理器的返回值
+func (r *failoverSink) handle(record *contract.Record) (ok bool, result bool) {
+	defer func() {
+		if a := recover(); a != nil {
+			r.fail(&contract.Error{Handler: report.Name(r.handler), Kind: contract.ErrorKindPanic, Record: record, Err: report.Panic(a)})
+			ok, result = false, false
+		}
+	}()
+	result, err := r.handler.TryHandle(record)
+	if err != nil {
+		r.fail(err)
+		return false, false
+	}
+	//写入成功，恢复健康
+	atomic.StoreInt64(&r.until, 0)
+	return true, result
+}
+
+func (r *FailoverHandler) Handle(record *contract.Record) bool {
+	level := contract.GetLevelByName(record.Level)
+	now := time.Now().UnixNano()
+	for _, sink := range r.sinks {
+		if !sink.handler.IsHandling(level) || !sink.available(now) {
+			continue
+		}
+		if ok, result := sink.handle(record); ok {
+			return result
+		}
+	}
+	atomic.AddUint64(&r.failed, 1)
+	//所有日志处理器都写入失败，日志继续交给下一个日志处理器
+	return false
+}
+
+func (r *FailoverHandler) IsHandling(level contract.Level) bool {
+	for _, sink := range r.sinks {
+		if sink.handler.IsHandling(level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Failed 返回所有日志处理器都写入失败或者不健康的日志数量
+func (r *FailoverHandler) Failed() uint64 {
+	return atomic.LoadUint64(&r.failed)
+}
+
+// Active 返回当前使用的日志处理器，即第一个健康的日志处理器，都不健康时返回 nil
+func (r *FailoverHandler) Active() contract.Handler {
+	for _, sink := range r.sinks {
+		if atomic.LoadInt64(&sink.until) == 0 {
+			return sink.handler
+		}
+	}
+	return nil
+}
+
+// FailoverStatus 被故障转移的日志处理器的健康状态
+type FailoverStatus struct {
+	//日志处理器名称
+	Name string
+	//日志处理器
+	Handler contract.Handler
+	//是否健康
+	Healthy bool
+	//写入失败的次数
+	Failures uint64
+	//不健康的日志处理器下次试探的时间
+	RetryAt time.Time
+}
+
+// String 返回健康状态的描述，便于输出
+func (r FailoverStatus) String() string {
+	if r.Healthy {
+		return fmt.Sprintf("%s: healthy, %d failures", r.Name, r.Failures)
+	}
+	return fmt.Sprintf("%s: unhealthy until %s, %d failures", r.Name, r.RetryAt.Format(time.RFC3339), r.Failures)
+}
+
+// Status 按尝试顺序返回各个日志处理器的健康状态
+func (r *FailoverHandler) Status() []FailoverStatus {
+	tmp := make([]FailoverStatus, 0, len(r.sinks))
+	for _, sink := range r.sinks {
+		status := FailoverStatus{Name: report.Name(sink.handler), Handler: sink.handler, Healthy: true, Failures: atomic.LoadUint64(&sink.failures)}
+		if until := atomic.LoadInt64(&sink.until); until != 0 {
+			status.Healthy = false
+			status.RetryAt = time.Unix(0, until)
+		}
+		tmp = append(tmp, status)
+	}
+	return tmp
+}
+
+// Close 关闭所有被包装的日志处理器
+func (r *FailoverHandler) Close() error {
+	bag := bytes.Buffer{}
+	for _, sink := range r.sinks {
+		if e := sink.handler.Close(); e != nil {
+			bag.WriteString(e.Error())
+			bag.WriteByte('\n')
+		}
+	}
+	if bag.Len() > 0 {
+		return errors.New(bag.String())
+	}
+	return nil
+}
+
+// Flush 冲刷所有被包装的日志处理器
+func (r *FailoverHandler) Flush(ctx context.Context) error {
+	bag := bytes.Buffer{}
+	for _, sink := range r.sinks {
+		if flusher, ok := sink.handler.(contract.Flusher); ok {
+			if e := flusher.Flush(ctx); e != nil {
+				bag.WriteString(e.Error())
+				bag.WriteByte('\n')
+			}
+		}
+	}
+	if bag.Len() > 0 {
+		return errors.New(bag.String())
+	}
+	return nil
+}
+
+// SetLevel 修改所有被包装的日志处理器的日志等级
+func (r *FailoverHandler) SetLevel(level contract.Level) {
+	for _, sink := range r.sinks {
+		if leveler, ok := sink.handler.(contract.Leveler); ok {
+			leveler.SetLevel(level)
+		}
+	}
+}
+
+// GetLevel 返回主日志处理器的日志等级
+func (r *FailoverHandler) GetLevel() contract.Level {
+	if len(r.sinks) > 0 {
+		if leveler, ok := r.sinks[0].handler.(contract.Leveler); ok {
+			return leveler.GetLevel()
+		}
+	}
+	return contract.LevelDebug
+}
+
+// SetErrorHandler 设置写入错误的错误处理器，同时设置被包装的日志处理器的错误处理器，可以在运行时安全调用
+func (r *FailoverHandler) SetErrorHandler(handler contract.ErrorHandler) {
+	r.errorHandler.SetErrorHandler(handler)
+	for _, sink := range r.sinks {
+		if setter, ok := sink.handler.(contract.ErrorHandlerSetter); ok {
+			setter.SetErrorHandler(handler)
+		}
+	}
+}
+
+func (r *FailoverHandler) GetErrorHandler() contract.ErrorHandler {
+	return r.errorHandler.GetErrorHandler()
+}
@@ -0,0 +1,164 @@
+package flog_test
+
+import (
+	"errors"
+	"github.com/buexplain/go-flog"
+	"github.com/buexplain/go-flog/contract"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 可以模拟写入失败的日志处理器，写入失败时同步返回错误
+type flakyHandler struct {
+	*memoryHandler
+	//0 正常写入，1 返回错误，2 恐慌
+	mode         int32
+	errorHandler contract.AtomicErrorHandler
+}
+
+func newFlakyHandler() *flakyHandler {
+	return &flakyHandler{memoryHandler: newMemoryHandler(contract.LevelDebug)}
+}
+
+func (r *flakyHandler) Handle(record *contract.Record) bool {
+	bubble, err := r.TryHandle(record)
+	if err != nil {
+		r.errorHandler.GetErrorHandler().HandleError(err)
+	}
+	return bubble
+}
+
+func (r *flakyHandler) TryHandle(record *contract.Record) (bool, *contract.Error) {
+	switch atomic.LoadInt32(&r.mode) {
+	case 1:
+		return false, &contract.Error{Handler: "flaky", Kind: contract.ErrorKindRequest, Record: record, Err: errors.New("unavailable")}
+	case 2:
+		panic("flaky panic")
+	}
+	return r.memoryHandler.Handle(record), nil
+}
+
+func (r *flakyHandler) SetErrorHandler(handler contract.ErrorHandler) {
+	r.errorHandler.SetErrorHandler(handler)
+}
+
+func (r *flakyHandler) GetErrorHandler() contract.ErrorHandler {
+	return r.errorHandler.GetErrorHandler()
+}
+
+func TestFailoverHandler(t *testing.T) {
+	primary := newFlakyHandler()
+	secondary := newFlakyHandler()
+	failover, err := flog.NewFailoverHandler(primary, secondary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failover.SetCooldown(50 * time.Millisecond)
+	var reported int32
+	failover.SetErrorHandler(contract.ErrorHandlerFunc(func(err *contract.Error) {
+		atomic.AddInt32(&reported, 1)
+	}))
+	logger := flog.New("failover", failover)
+	logger.Info("primary")
+	if failover.Active() != primary || len(primary.getRecords()) != 1 {
+		t.Fatal("主日志处理器健康时应该写入主日志处理器")
+	}
+
+	//主日志处理器写入失败，当前日志转交给备用日志处理器，冷却时间内跳过主日志处理器
+	atomic.StoreInt32(&primary.mode, 1)
+	logger.Info("failed")
+	logger.Info("cooldown")
+	if got := messages(secondary.getRecords()); len(got) != 2 || got[0] != "failed" || got[1] != "cooldown" {
+		t.Error("写入失败的日志应该转交给备用日志处理器", got)
+	}
+	if failover.Active() != secondary || atomic.LoadInt32(&reported) != 1 {
+		t.Error("写入失败的日志处理器应该被标记为不健康，并且报告内部错误", atomic.LoadInt32(&reported))
+	}
+	status := failover.Status()
+	if len(status) != 2 || status[0].Healthy || status[0].Failures != 1 || !status[1].Healthy || status[0].RetryAt.IsZero() {
+		t.Error("健康状态错误", status)
+	}
+
+	//冷却时间结束后试探，试探失败再次冷却
+	time.Sleep(60 * time.Millisecond)
+	atomic.StoreInt32(&primary.mode, 2)
+	logger.Info("panic")
+	if len(secondary.getRecords()) != 3 || failover.Active() != secondary || atomic.LoadInt32(&reported) != 2 {
+		t.Error("试探时恐慌应该转交给备用日志处理器")
+	}
+
+	//试探成功后恢复健康
+	time.Sleep(60 * time.Millisecond)
+	atomic.StoreInt32(&primary.mode, 0)
+	logger.Info("recovered")
+	if failover.Active() != primary || len(primary.getRecords()) != 2 || len(secondary.getRecords()) != 3 {
+		t.Error("试探成功后应该恢复使用主日志处理器")
+	}
+	if err := logger.Close(); err != nil {
+		t.Error(err)
+	}
+	if primary.closed != 1 || secondary.closed != 1 {
+		t.Error("应该关闭所有被包装的日志处理器")
+	}
+}
+
+func TestFailoverHandlerAllFailed(t *testing.T) {
+	primary := newFlakyHandler()
+	atomic.StoreInt32(&primary.mode, 1)
+	failover, err := flog.NewFailoverHandler(primary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failover.SetErrorHandler(contract.ErrorHandlerFunc(func(err *contract.Error) {}))
+	next := newMemoryHandler(contract.LevelDebug)
+	logger := flog.New("failover", failover)
+	logger.PushHandler(next)
+	logger.Error("error")
+	logger.Error("error")
+	//所有日志处理器都失败时，日志继续交给下一个日志处理器
+	if failover.Failed() != 2 || failover.Active() != nil || len(next.getRecords()) != 2 {
+		t.Error("所有日志处理器都写入失败时的处理错误", failover.Failed())
+	}
+	if err := logger.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFailoverHandlerUnsupported(t *testing.T) {
+	//不能同步返回写入错误的日志处理器不能被故障转移
+	if _, err := flog.NewFailoverHandler(newFlakyHandler(), newMemoryHandler(contract.LevelDebug)); err == nil {
+		t.Error("没有实现 contract.TryHandler 的日志处理器应该返回错误")
+	}
+}
+
+func TestFailoverHandlerErrorHandler(t *testing.T) {
+	primary := newFlakyHandler()
+	secondary := newFlakyHandler()
+	failover, err := flog.NewFailoverHandler(primary, secondary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := flog.New("failover", failover)
+	var reported int32
+	failover.SetErrorHandler(contract.ErrorHandlerFunc(func(err *contract.Error) {
+		atomic.AddInt32(&reported, 1)
+	}))
+	if primary.GetErrorHandler() == nil {
+		t.Error("应该同时设置被包装的日志处理器的错误处理器")
+	}
+	//之后被包装的日志处理器替换了自己的错误处理器，也不影响故障转移
+	var own int32
+	primary.SetErrorHandler(contract.ErrorHandlerFunc(func(err *contract.Error) {
+		atomic.AddInt32(&own, 1)
+	}))
+	atomic.StoreInt32(&primary.mode, 1)
+	logger.Info("failed")
+	if len(secondary.getRecords()) != 1 || failover.Active() != secondary {
+		t.Error("被包装的日志处理器替换错误处理器后应该仍然可以故障转移")
+	}
+	if atomic.LoadInt32(&reported) != 1 || atomic.LoadInt32(&own) != 0 {
+		t.Error("写入错误应该交给包装器的错误处理器", atomic.LoadInt32(&reported), atomic.LoadInt32(&own))
+	}
+	_ = logger.Close()
+}
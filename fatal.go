@@ -0,0 +1,70 @@
+package flog
+
+import (
+	"context"
+	"fmt"
+	"github.com/buexplain/go-flog/contract"
+	"os"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+// Fatal 以 Emergency 等级写入日志，冲刷所有日志处理器后调用 os.Exit(1) 退出进程
+// 异步队列与日志处理器缓冲区中的日志会在退出前写入目的地，冲刷的等待时间不超过 Close 的超时时间
+func (r *Logger) Fatal(message string, context ...interface{}) {
+	r.addRecord(nil, contract.LevelEmergency, false, message, context, nil)
+	r.flushBeforeExit()
+	os.Exit(1)
+}
+
+func (r *Logger) FatalF(format string, v ...interface{}) {
+	r.addRecord(nil, contract.LevelEmergency, true, format, v, nil)
+	r.flushBeforeExit()
+	os.Exit(1)
+}
+
+// Panic 以 Critical 等级写入日志，冲刷所有日志处理器后以日志信息 panic
+func (r *Logger) Panic(message string, context ...interface{}) {
+	r.addRecord(nil, contract.LevelCritical, false, message, context, nil)
+	r.flushBeforeExit()
+	panic(message)
+}
+
+func (r *Logger) PanicF(format string, v ...interface{}) {
+	r.addRecord(nil, contract.LevelCritical, true, format, v, nil)
+	r.flushBeforeExit()
+	panic(fmt.Sprintf(format, resolveArgs(v)...))
+}
+
+// Recover 用于 defer，捕获 panic 后以 Critical 等级写入日志，附加信息 Stack 为完整的调用栈，冲刷所有日志处理器
+// 日志的调用位置是发生 panic 的位置，repanic 为 true 时，写入日志后以原来的值再次 panic，用法：defer logger.Recover(false)
+func (r *Logger) Recover(repanic bool) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	logger := r.recovered()
+	logger.addRecord(nil, contract.LevelCritical, false, fmt.Sprintf("panic: %v", v), []interface{}{v}, nil)
+	r.flushBeforeExit()
+	if repanic {
+		panic(v)
+	}
+}
+
+// 返回绑定了调用栈与发生 panic 的位置的子日志收集器，必须由 Recover 直接调用
+func (r *Logger) recovered() *Logger {
+	tmp := r.With("Stack", string(debug.Stack()))
+	//跳过 recovered 与 Recover，再跳过 runtime 包内处理 panic 的函数
+	tmp.caller = callerFrame(2, "runtime.")
+	return tmp
+}
+
+// 冲刷所有日志处理器，等待时间不超过 Close 的超时时间
+func (r *Logger) flushBeforeExit() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(atomic.LoadInt64(&r.root.timeout)))
+	defer cancel()
+	if err := r.Flush(ctx); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, "flog: flush before exit error:", err)
+	}
+}
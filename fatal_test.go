@@ -0,0 +1,169 @@
+package flog_test
+
+import (
+	"errors"
+	"github.com/buexplain/go-flog"
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/extra"
+	"github.com/buexplain/go-flog/formatter"
+	"github.com/buexplain/go-flog/handler"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoggerFatal(t *testing.T) {
+	//子进程写入日志后退出
+	if path := os.Getenv("FLOG_FATAL_PATH"); path != "" {
+		file := handler.NewFile(contract.LevelDebug, formatter.NewLine(), path)
+		//缓冲区足够大并且不会定时冲刷，只有 Fatal 的冲刷才能把日志写入文件
+		file.SetBuffer(4096, time.Hour)
+		logger := flog.New("fatal", file)
+		logger.Async(100)
+		logger.Info("before fatal")
+		logger.FatalF("fatal %d", 1)
+		return
+	}
+	path, err := os.MkdirTemp("", "flog-fatal")
+	if err != nil {
+		t.Fatal("构建临时目录失败", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(path)
+	}()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestLoggerFatal$")
+	cmd.Env = append(os.Environ(), "FLOG_FATAL_PATH="+path)
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		t.Fatal("期待子进程以状态码 1 退出", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(path, "*.log"))
+	if len(files) != 1 {
+		t.Fatal("没有写入日志文件", files)
+	}
+	content, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(content), "before fatal") || !strings.Contains(string(content), "fatal 1") {
+		t.Error("退出前没有冲刷日志", string(content))
+	}
+}
+
+func TestLoggerPanic(t *testing.T) {
+	memory := newMemoryHandler(contract.LevelDebug)
+	logger := flog.New("panic", memory)
+	logger.Async(100)
+	defer func() {
+		_ = logger.Close(10 * time.Millisecond)
+	}()
+	func() {
+		defer func() {
+			if v := recover(); v != "panic 1" {
+				t.Error("panic 的值错误", v)
+			}
+		}()
+		logger.PanicF("panic %d", 1)
+	}()
+	//panic 之前已经冲刷，异步队列中的日志已经写入
+	records := memory.getRecords()
+	if len(records) != 1 || records[0].Message != "panic 1" || records[0].Level != "critical" || memory.flushed == 0 {
+		t.Error("panic 之前没有冲刷日志", len(records), memory.flushed)
+	}
+}
+
+func TestLoggerPanicFValuer(t *testing.T) {
+	memory := newMemoryHandler(contract.LevelDebug)
+	logger := flog.New("panic", memory)
+	lazy := flog.Valuer(func() interface{} {
+		return "lazy"
+	})
+	func() {
+		defer func() {
+			//panic 的值与日志信息一致，延迟求值的参数已经求值
+			if v := recover(); v != "panic lazy" {
+				t.Error("panic 的值错误", v)
+			}
+		}()
+		logger.PanicF("panic %v", lazy)
+	}()
+	records := memory.getRecords()
+	if len(records) != 1 || records[0].Message != "panic lazy" {
+		t.Error("panic 的日志信息错误", records)
+	}
+}
+
+func TestLoggerRecover(t *testing.T) {
+	memory := newMemoryHandler(contract.LevelDebug)
+	logger := flog.New("recover", memory)
+	boom := errors.New("boom")
+	func() {
+		defer logger.Recover(false)
+		panic(boom)
+	}()
+	func() {
+		defer func() {
+			if v := recover(); v != "again" {
+				t.Error("没有再次 panic", v)
+			}
+		}()
+		defer logger.Recover(true)
+		panic("again")
+	}()
+	func() {
+		//没有 panic 时不写入日志
+		defer logger.Recover(false)
+	}()
+	records := memory.getRecords()
+	if len(records) != 2 {
+		t.Fatalf("期待收集到 2 条日志，实际收集到 %d 条", len(records))
+	}
+	if records[0].Message != "panic: boom" || records[0].Level != "critical" || !isError(records[0].Context, boom) {
+		t.Error("捕获的 panic 日志错误", records[0].Message, records[0].Context)
+	}
+	if stack, _ := records[0].Extra["Stack"].(string); !strings.Contains(stack, "fatal_test.go") {
+		t.Error("捕获的 panic 日志没有调用栈", records[0].Extra)
+	}
+	if records[1].Message != "panic: again" {
+		t.Error("捕获的 panic 日志错误", records[1].Message)
+	}
+}
+
+func TestLoggerRecoverCaller(t *testing.T) {
+	memory := newMemoryHandler(contract.LevelDebug)
+	logger := flog.New("recover", memory, extra.NewFuncCaller())
+	old := flog.Default()
+	defer flog.SetDefault(old)
+	flog.SetDefault(logger)
+	var lines []int
+	func() {
+		defer logger.Recover(false)
+		_, _, line, _ := runtime.Caller(0)
+		lines = append(lines, line+2)
+		panic("logger")
+	}()
+	func() {
+		defer flog.Recover(false)
+		_, _, line, _ := runtime.Caller(0)
+		lines = append(lines, line+2)
+		panic("default")
+	}()
+	records := memory.getRecords()
+	if len(records) != 2 {
+		t.Fatalf("期待收集到 2 条日志，实际收集到 %d 条", len(records))
+	}
+	//日志的调用位置是发生 panic 的位置
+	for i, record := range records {
+		if file, _ := record.Extra["File"].(string); !strings.HasSuffix(file, "fatal_test.go") || record.Extra["Line"] != lines[i] {
+			t.Error("捕获的 panic 日志的调用位置错误", record.Extra["File"], record.Extra["Line"], lines[i])
+		}
+	}
+}
+
+// 判断日志上下文是否是指定的错误，错误会被转换为 *contract.ErrorDetail
+func isError(context interface{}, target error) bool {
+	err, ok := context.(error)
+	return ok && errors.Is(err, target)
+}
@@ -0,0 +1,116 @@
+package flog
+
+import (
+	"github.com/buexplain/go-flog/contract"
+	"math"
+	"time"
+)
+
+// String 字符串字段
+func String(key string, value string) contract.Field {
+	return contract.Field{Key: key, Type: contract.FieldTypeString, String: value}
+}
+
+// Int 整数字段
+func Int(key string, value int) contract.Field {
+	return contract.Field{Key: key, Type: contract.FieldTypeInt64, Integer: int64(value)}
+}
+
+// Int64 64位整数字段
+func Int64(key string, value int64) contract.Field {
+	return contract.Field{Key: key, Type: contract.FieldTypeInt64, Integer: value}
+}
+
+// Uint 无符号整数字段
+func Uint(key string, value uint) contract.Field {
+	return contract.Field{Key: key, Type: contract.FieldTypeUint64, Integer: int64(value)}
+}
+
+// Uint64 64位无符号整数字段
+func Uint64(key string, value uint64) contract.Field {
+	return contract.Field{Key: key, Type: contract.FieldTypeUint64, Integer: int64(value)}
+}
+
+// Float64 浮点数字段
+func Float64(key string, value float64) contract.Field {
+	return contract.Field{Key: key, Type: contract.FieldTypeFloat64, Integer: int64(math.Float64bits(value))}
+}
+
+// Bool 布尔字段
+func Bool(key string, value bool) contract.Field {
+	var i int64
+	if value {
+		i = 1
+	}
+	return contract.Field{Key: key, Type: contract.FieldTypeBool, Integer: i}
+}
+
+// Duration 时间间隔字段
+func Duration(key string, value time.Duration) contract.Field {
+	return contract.Field{Key: key, Type: contract.FieldTypeDuration, Integer: int64(value)}
+}
+
+// Time 时间字段
+func Time(key string, value time.Time) contract.Field {
+	return contract.Field{Key: key, Type: contract.FieldTypeTime, Interface: value}
+}
+
+// Err 以 error 为键的错误字段
+func Err(err error) contract.Field {
+	return NamedErr("error", err)
+}
+
+// NamedErr 指定键的错误字段
+func NamedErr(key string, err error) contract.Field {
+	if err == nil {
+		return contract.Field{Key: key, Type: contract.FieldTypeUnknown}
+	}
+	return contract.Field{Key: key, Type: contract.FieldTypeError, Interface: err}
+}
+
+// Any 任意值字段，能识别的类型会转为对应的强类型字段，其余类型在格式化时退化为反射
+func Any(key string, value interface{}) contract.Field {
+	switch v := value.(type) {
+	case nil:
+		return contract.Field{Key: key, Type: contract.FieldTypeUnknown}
+	case contract.Field:
+		v.Key = key
+		return v
+	case string:
+		return String(key, v)
+	case int:
+		return Int(key, v)
+	case int8:
+		return Int64(key, int64(v))
+	case int16:
+		return Int64(key, int64(v))
+	case int32:
+		return Int64(key, int64(v))
+	case int64:
+		return Int64(key, v)
+	case uint:
+		return Uint(key, v)
+	case uint8:
+		return Uint64(key, uint64(v))
+	case uint16:
+		return Uint64(key, uint64(v))
+	case uint32:
+		return Uint64(key, uint64(v))
+	case uint64:
+		return Uint64(key, v)
+	case float32:
+		return Float64(key, float64(v))
+	case float64:
+		return Float64(key, v)
+	case bool:
+		return Bool(key, v)
+	case time.Duration:
+		return Duration(key, v)
+	case time.Time:
+		return Time(key, v)
+	case error:
+		return NamedErr(key, v)
+	default:
+		return contract.Field{Key: key, Type: contract.FieldTypeAny, Interface: value}
+	}
+}
@@ -0,0 +1,90 @@
+package flog
+
+import (
+	"context"
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/internal/report"
+)
+
+// 可以按日志等级提前判断的日志过滤器
+type levelFilter interface {
+	IsHandling(level contract.Level) bool
+}
+
+// FilterHandler 对日志进行过滤的日志处理器包装器，只对被包装的日志处理器生效
+// 日志过滤器如果实现了 IsHandling(level contract.Level) bool，比如 filter.LevelRange，也会参与判断是否可以处理日志
+type FilterHandler struct {
+	handler contract.Handler
+	filters []contract.Filter
+}
+
+// NewFilterHandler 包装日志处理器，通过所有日志过滤器的日志才会交给被包装的日志处理器
+func NewFilterHandler(handler contract.Handler, filters ...contract.Filter) *FilterHandler {
+	tmp := new(FilterHandler)
+	tmp.handler = handler
+	tmp.filters = filters
+	return tmp
+}
+
+func (r *FilterHandler) Handle(record *contract.Record) bool {
+	for _, v := range r.filters {
+		if !v.Filter(record) {
+			//被过滤的日志继续交给下一个日志处理器
+			return false
+		}
+	}
+	return r.handler.Handle(record)
+}
+
+func (r *FilterHandler) IsHandling(level contract.Level) bool {
+	for _, v := range r.filters {
+		if leveler, ok := v.(levelFilter); ok && !leveler.IsHandling(level) {
+			return false
+		}
+	}
+	return r.handler.IsHandling(level)
+}
+
+func (r *FilterHandler) Close() error {
+	return r.handler.Close()
+}
+
+func (r *FilterHandler) Flush(ctx context.Context) error {
+	if flusher, ok := r.handler.(contract.Flusher); ok {
+		return flusher.Flush(ctx)
+	}
+	return nil
+}
+
+// SetLevel 修改被包装的日志处理器的日志等级
+func (r *FilterHandler) SetLevel(level contract.Level) {
+	if leveler, ok := r.handler.(contract.Leveler); ok {
+		leveler.SetLevel(level)
+	}
+}
+
+func (r *FilterHandler) GetLevel() contract.Level {
+	if leveler, ok := r.handler.(contract.Leveler); ok {
+		return leveler.GetLevel()
+	}
+	return contract.LevelDebug
+}
+
+// SetErrorHandler 设置被包装的日志处理器的错误处理器
+func (r *FilterHandler) SetErrorHandler(handler contract.ErrorHandler) {
+	if setter, ok := r.handler.(contract.ErrorHandlerSetter); ok {
+		setter.SetErrorHandler(handler)
+	}
+}
+
+func (r *FilterHandler) GetErrorHandler() contract.ErrorHandler {
+	if setter, ok := r.handler.(contract.ErrorHandlerSetter); ok {
+		return setter.GetErrorHandler()
+	}
+	return nil
+}
+
+// GetName 返回被包装的日志处理器的名称
+func (r *FilterHandler) GetName() string {
+	return report.Name(r.handler)
+}
@@ -0,0 +1,33 @@
+package filter
+
+import "github.com/buexplain/go-flog/contract"
+
+// Channel 按日志通道过滤日志
+type Channel struct {
+	channels map[string]struct{}
+	//true 表示只保留名单内的日志通道，false 表示丢弃名单内的日志通道
+	allow bool
+}
+
+// NewChannelAllow 只保留名单内的日志通道的日志
+func NewChannelAllow(channels ...string) *Channel {
+	return newChannel(true, channels)
+}
+
+// NewChannelDeny 丢弃名单内的日志通道的日志
+func NewChannelDeny(channels ...string) *Channel {
+	return newChannel(false, channels)
+}
+
+func newChannel(allow bool, channels []string) *Channel {
+	tmp := &Channel{channels: make(map[string]struct{}, len(channels)), allow: allow}
+	for _, v := range channels {
+		tmp.channels[v] = struct{}{}
+	}
+	return tmp
+}
+
+func (r *Channel) Filter(record *contract.Record) bool {
+	_, ok := r.channels[record.Channel]
+	return ok == r.allow
+}
@@ -0,0 +1,33 @@
+package filter
+
+import (
+	"github.com/buexplain/go-flog/contract"
+	"reflect"
+)
+
+// Extra 按附加信息的键值过滤日志，只保留附加信息中存在该键并且值相等的日志
+type Extra struct {
+	key   string
+	value interface{}
+}
+
+func NewExtra(key string, value interface{}) *Extra {
+	return &Extra{key: key, value: value}
+}
+
+func (r *Extra) Filter(record *contract.Record) bool {
+	v, ok := record.Extra[r.key]
+	if !ok {
+		return false
+	}
+	//按深度比较，不可比较的值也不会引发 panic
+	return reflect.DeepEqual(v, r.value)
+}
+
+// ExtraExists 只保留附加信息中存在该键的日志
+type ExtraExists string
+
+func (r ExtraExists) Filter(record *contract.Record) bool {
+	_, ok := record.Extra[string(r)]
+	return ok
+}
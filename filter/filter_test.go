@@ -0,0 +1,45 @@
+package filter_test
+
+import (
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/filter"
+	"regexp"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	record := contract.NewRecord()
+	record.Channel = "payment"
+	record.Level = contract.GetNameByLevel(contract.LevelWarning)
+	record.Message = "GET /health 200"
+	record.Extra["userID"] = 100
+	record.Extra["tags"] = []string{"a"}
+	tests := []struct {
+		filter contract.Filter
+		expect bool
+	}{
+		{filter.NewChannelAllow("payment", "order"), true},
+		{filter.NewChannelAllow("order"), false},
+		{filter.NewChannelDeny("payment"), false},
+		{filter.NewChannelDeny("order"), true},
+		{filter.NewMessageAllow(regexp.MustCompile(`^GET `)), true},
+		{filter.NewMessageDeny(regexp.MustCompile(`/health`)), false},
+		{filter.NewExtra("userID", 100), true},
+		{filter.NewExtra("userID", "100"), false},
+		{filter.NewExtra("tags", []string{"a"}), true},
+		{filter.NewExtra("missing", nil), false},
+		{filter.ExtraExists("userID"), true},
+		{filter.NewLevelRange(contract.LevelError, contract.LevelWarning), true},
+		{filter.NewLevelRange(contract.LevelNotice, contract.LevelDebug), false},
+		{filter.Not(filter.NewChannelAllow("payment")), false},
+	}
+	for i, v := range tests {
+		if v.filter.Filter(record) != v.expect {
+			t.Errorf("用例 %d 期待过滤结果为 %v", i, v.expect)
+		}
+	}
+	levelRange := filter.NewLevelRange(contract.LevelWarning, contract.LevelError)
+	if levelRange.IsHandling(contract.LevelCritical) || !levelRange.IsHandling(contract.LevelError) || levelRange.IsHandling(contract.LevelNotice) {
+		t.Error("日志等级区间判断错误")
+	}
+}
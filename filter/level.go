@@ -0,0 +1,28 @@
+package filter
+
+import "github.com/buexplain/go-flog/contract"
+
+// LevelRange 只保留日志等级在区间内的日志，区间包含两端
+type LevelRange struct {
+	//区间内最严重的日志等级
+	high contract.Level
+	//区间内最详细的日志等级
+	low contract.Level
+}
+
+// NewLevelRange 创建日志等级区间，两端的顺序不限，比如：NewLevelRange(contract.LevelWarning, contract.LevelError)
+func NewLevelRange(a, b contract.Level) *LevelRange {
+	if a > b {
+		a, b = b, a
+	}
+	return &LevelRange{high: a, low: b}
+}
+
+// IsHandling 判断日志等级是否在区间内，flog.FilterHandler 会据此提前跳过区间外的日志
+func (r *LevelRange) IsHandling(level contract.Level) bool {
+	return level >= r.high && level <= r.low
+}
+
+func (r *LevelRange) Filter(record *contract.Record) bool {
+	return r.IsHandling(contract.GetLevelByName(record.Level))
+}
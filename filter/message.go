@@ -0,0 +1,27 @@
+package filter
+
+import (
+	"github.com/buexplain/go-flog/contract"
+	"regexp"
+)
+
+// Message 按日志信息的正则表达式过滤日志
+type Message struct {
+	expr *regexp.Regexp
+	//true 表示只保留匹配的日志，false 表示丢弃匹配的日志
+	allow bool
+}
+
+// NewMessageAllow 只保留信息匹配正则表达式的日志
+func NewMessageAllow(expr *regexp.Regexp) *Message {
+	return &Message{expr: expr, allow: true}
+}
+
+// NewMessageDeny 丢弃信息匹配正则表达式的日志，比如健康检查之类的噪音日志
+func NewMessageDeny(expr *regexp.Regexp) *Message {
+	return &Message{expr: expr, allow: false}
+}
+
+func (r *Message) Filter(record *contract.Record) bool {
+	return r.expr.MatchString(record.Message) == r.allow
+}
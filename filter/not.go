@@ -0,0 +1,10 @@
+package filter
+
+import "github.com/buexplain/go-flog/contract"
+
+// Not 取反日志过滤器的结果
+func Not(filter contract.Filter) contract.Filter {
+	return contract.FilterFunc(func(record *contract.Record) bool {
+		return !filter.Filter(record)
+	})
+}
@@ -0,0 +1,49 @@
+package flog_test
+
+import (
+	"github.com/buexplain/go-flog"
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/filter"
+	"regexp"
+	"testing"
+)
+
+func TestLoggerFilter(t *testing.T) {
+	memory := newMemoryHandler(contract.LevelDebug)
+	logger := flog.New("filter", memory)
+	logger.PushFilter(filter.NewMessageDeny(regexp.MustCompile(`/health`)))
+	logger.PushFilter(filter.Not(filter.NewExtra("internal", true)))
+	logger.Info("GET /health 200")
+	logger.With("internal", true).Info("internal")
+	logger.Info("GET /order 200")
+	records := memory.getRecords()
+	if len(records) != 1 || records[0].Message != "GET /order 200" {
+		t.Error("日志收集器的日志过滤器没有生效", len(records))
+	}
+	if len(logger.GetFilters()) != 2 || logger.PopFilter() == nil || len(logger.GetFilters()) != 1 {
+		t.Error("弹出日志过滤器失败")
+	}
+}
+
+func TestFilterHandler(t *testing.T) {
+	//只把 warning 到 error 的日志交给 alarm
+	alarm := newMemoryHandler(contract.LevelDebug)
+	all := newMemoryHandler(contract.LevelDebug)
+	logger := flog.New("filter", flog.NewFilterHandler(alarm, filter.NewLevelRange(contract.LevelWarning, contract.LevelError)))
+	logger.PushHandler(flog.NewFilterHandler(all, filter.NewChannelDeny("health")))
+	logger.Critical("critical")
+	logger.Error("error")
+	logger.Warning("warning")
+	logger.Info("info")
+	logger.WithChannel("health").Warning("health")
+	records := alarm.getRecords()
+	if len(records) != 3 || records[0].Message != "error" || records[1].Message != "warning" || records[2].Message != "health" {
+		t.Error("日志处理器的日志过滤器没有生效", len(records))
+	}
+	if n := len(all.getRecords()); n != 4 {
+		t.Errorf("期待收集到 4 条日志，实际收集到 %d 条", n)
+	}
+	if err := logger.Close(); err != nil || alarm.closed != 1 {
+		t.Error("没有关闭被包装的日志处理器", err)
+	}
+}
@@ -0,0 +1,187 @@
+package flog
+
+import (
+	"context"
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/internal/report"
+	"sync"
+)
+
+// FingersCrossedHandler 缓冲日志的日志处理器包装器，参考 Monolog 的 FingersCrossedHandler
+// 日志先缓冲在内存中不写入，收到等于或高于触发等级的日志时，将缓冲的日志连同该日志一起交给被包装的日志处理器，之后的日志直接透传
+// 这样只有出错时才写入错误前后完整的调试日志，正常情况下不需要为调试日志付出写入的代价
+// 一般用于一次请求、一个任务这样的作用域，作用域结束时调用 Reset 清空缓冲并恢复缓冲状态
+type FingersCrossedHandler struct {
+	handler contract.Handler
+	//触发等级
+	activation contract.Level
+	//缓冲的日志数量上限，超出后丢弃最早的日志，0表示不限制
+	bufferSize int
+	//触发后是否停止缓冲，true 表示直到调用 Reset 之前一直透传，false 表示写入缓冲的日志后立即恢复缓冲
+	stopBuffering bool
+	lock          *sync.Mutex
+	//缓冲的日志，bufferSize 大于0时是环形缓冲区
+	buffer []*contract.Record
+	//环形缓冲区中最早的日志的下标
+	start int
+	//是否已经触发
+	activated bool
+}
+
+// NewFingersCrossedHandler 包装日志处理器，收到等于或高于 activation 等级的日志时触发，最多缓冲 bufferSize 条日志，0表示不限制
+// 缓冲的日志持有引用，不限制缓冲数量时，长时间不触发也不调用 Reset 会持续占用内存
+func NewFingersCrossedHandler(handler contract.Handler, activation contract.Level, bufferSize int) *FingersCrossedHandler {
+	tmp := new(FingersCrossedHandler)
+	tmp.handler = handler
+	tmp.activation = activation
+	if bufferSize > 0 {
+		tmp.bufferSize = bufferSize
+	}
+	tmp.stopBuffering = true
+	tmp.lock = new(sync.Mutex)
+	return tmp
+}
+
+// SetStopBuffering 设置触发后是否停止缓冲，默认为 true，直到调用 Reset 之前一直透传
+// 设置为 false 时，每次触发只写入缓冲的日志与触发的日志，然后立即恢复缓冲
+func (r *FingersCrossedHandler) SetStopBuffering(stopBuffering bool) *FingersCrossedHandler {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.stopBuffering = stopBuffering
+	return r
+}
+
+// Activated 判断是否已经触发并处于透传状态
+func (r *FingersCrossedHandler) Activated() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.activated
+}
+
+// Buffered 返回缓冲的日志数量
+func (r *FingersCrossedHandler) Buffered() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return len(r.buffer)
+}
+
+// Reset 丢弃缓冲的日志并恢复缓冲状态，用于作用域结束时
+func (r *FingersCrossedHandler) Reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.clear()
+	r.activated = false
+}
+
+// 丢弃缓冲的日志，调用方需要持有锁
+func (r *FingersCrossedHandler) clear() {
+	for _, v := range r.buffer {
+		v.Release()
+	}
+	r.buffer = nil
+	r.start = 0
+}
+
+// 缓冲日志，调用方需要持有锁
+func (r *FingersCrossedHandler) push(record *contract.Record) {
+	if r.bufferSize == 0 || len(r.buffer) < r.bufferSize {
+		r.buffer = append(r.buffer, record.Retain())
+		return
+	}
+	//缓冲区已满，覆盖最早的日志
+	r.buffer[r.start].Release()
+	r.buffer[r.start] = record.Retain()
+	r.start = (r.start + 1) % len(r.buffer)
+}
+
+// 按缓冲顺序将缓冲的日志交给被包装的日志处理器，调用方需要持有锁
+func (r *FingersCrossedHandler) drain() {
+	n := len(r.buffer)
+	for i := 0; i < n; i++ {
+		record := r.buffer[(r.start+i)%n]
+		if r.handler.IsHandling(contract.GetLevelByName(record.Level)) {
+			r.handler.Handle(record)
+		}
+	}
+	r.clear()
+}
+
+func (r *FingersCrossedHandler) Handle(record *contract.Record) bool {
+	level := contract.GetLevelByName(record.Level)
+	r.lock.Lock()
+	if r.activated {
+		r.lock.Unlock()
+		if !r.handler.IsHandling(level) {
+			return false
+		}
+		return r.handler.Handle(record)
+	}
+	if level > r.activation {
+		r.push(record)
+		r.lock.Unlock()
+		//缓冲的日志继续交给下一个日志处理器
+		return false
+	}
+	//触发，持有锁写入缓冲的日志，保证并发收到的日志排在缓冲的日志之后
+	defer r.lock.Unlock()
+	r.drain()
+	r.activated = r.stopBuffering
+	if !r.handler.IsHandling(level) {
+		return false
+	}
+	return r.handler.Handle(record)
+}
+
+// IsHandling 等于或高于触发等级的日志，以及被包装的日志处理器可以处理的日志
+func (r *FingersCrossedHandler) IsHandling(level contract.Level) bool {
+	return level <= r.activation || r.handler.IsHandling(level)
+}
+
+// Close 丢弃缓冲的日志后关闭被包装的日志处理器
+func (r *FingersCrossedHandler) Close() error {
+	r.lock.Lock()
+	r.clear()
+	r.lock.Unlock()
+	return r.handler.Close()
+}
+
+// Flush 冲刷被包装的日志处理器，缓冲的日志不会被写入
+func (r *FingersCrossedHandler) Flush(ctx context.Context) error {
+	if flusher, ok := r.handler.(contract.Flusher); ok {
+		return flusher.Flush(ctx)
+	}
+	return nil
+}
+
+// SetLevel 修改被包装的日志处理器的日志等级
+func (r *FingersCrossedHandler) SetLevel(level contract.Level) {
+	if leveler, ok := r.handler.(contract.Leveler); ok {
+		leveler.SetLevel(level)
+	}
+}
+
+func (r *FingersCrossedHandler) GetLevel() contract.Level {
+	if leveler, ok := r.handler.(contract.Leveler); ok {
+		return leveler.GetLevel()
+	}
+	return contract.LevelDebug
+}
+
+// SetErrorHandler 设置被包装的日志处理器的错误处理器
+func (r *FingersCrossedHandler) SetErrorHandler(handler contract.ErrorHandler) {
+	if setter, ok := r.handler.(contract.ErrorHandlerSetter); ok {
+		setter.SetErrorHandler(handler)
+	}
+}
+
+func (r *FingersCrossedHandler) GetErrorHandler() contract.ErrorHandler {
+	if setter, ok := r.handler.(contract.ErrorHandlerSetter); ok {
+		return setter.GetErrorHandler()
+	}
+	return nil
+}
+
+// GetName 返回被包装的日志处理器的名称
+func (r *FingersCrossedHandler) GetName() string {
+	return report.Name(r.handler)
+}
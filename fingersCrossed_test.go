@@ -0,0 +1,78 @@
+package flog_test
+
+import (
+	"github.com/buexplain/go-flog"
+	"github.com/buexplain/go-flog/contract"
+	"strconv"
+	"testing"
+)
+
+// 收集的日志信息
+func messages(records []*contract.Record) []string {
+	tmp := make([]string, 0, len(records))
+	for _, v := range records {
+		tmp = append(tmp, v.Message)
+	}
+	return tmp
+}
+
+func TestFingersCrossedHandler(t *testing.T) {
+	memory := newMemoryHandler(contract.LevelDebug)
+	fingersCrossed := flog.NewFingersCrossedHandler(memory, contract.LevelError, 3)
+	logger := flog.New("fingersCrossed", fingersCrossed)
+	for i := 0; i < 5; i++ {
+		logger.Debug("debug" + strconv.Itoa(i))
+	}
+	logger.Info("info")
+	if len(memory.getRecords()) != 0 || fingersCrossed.Buffered() != 3 || fingersCrossed.Activated() {
+		t.Fatal("触发之前的日志应该被缓冲", fingersCrossed.Buffered())
+	}
+	//触发后按顺序写入缓冲的日志，超出缓冲上限的最早的日志被丢弃
+	logger.Error("error")
+	logger.Debug("after")
+	if got := messages(memory.getRecords()); len(got) != 5 || got[0] != "debug3" || got[2] != "info" || got[3] != "error" || got[4] != "after" {
+		t.Error("触发后写入的日志错误", got)
+	}
+	if !fingersCrossed.Activated() || fingersCrossed.Buffered() != 0 {
+		t.Error("触发后应该透传日志")
+	}
+	//重置后恢复缓冲
+	fingersCrossed.Reset()
+	logger.Debug("reset")
+	if len(memory.getRecords()) != 5 || fingersCrossed.Buffered() != 1 {
+		t.Error("重置后应该恢复缓冲")
+	}
+	if err := logger.Close(); err != nil {
+		t.Error(err)
+	}
+	if len(memory.getRecords()) != 5 || fingersCrossed.Buffered() != 0 {
+		t.Error("关闭时应该丢弃缓冲的日志")
+	}
+}
+
+func TestFingersCrossedHandlerKeepBuffering(t *testing.T) {
+	memory := newMemoryHandler(contract.LevelInfo)
+	fingersCrossed := flog.NewFingersCrossedHandler(memory, contract.LevelError, 0).SetStopBuffering(false)
+	if !fingersCrossed.IsHandling(contract.LevelError) || fingersCrossed.IsHandling(contract.LevelDebug) {
+		t.Error("只处理被包装的日志处理器可以处理的日志与触发等级的日志")
+	}
+	logger := flog.New("fingersCrossed", fingersCrossed)
+	logger.Debug("debug")
+	logger.Info("info1")
+	logger.Critical("critical")
+	logger.Info("info2")
+	if got := messages(memory.getRecords()); len(got) != 2 || got[0] != "info1" || got[1] != "critical" {
+		t.Error("触发后写入的日志错误", got)
+	}
+	//写入缓冲的日志后立即恢复缓冲
+	if fingersCrossed.Activated() || fingersCrossed.Buffered() != 1 {
+		t.Error("触发后应该恢复缓冲")
+	}
+	logger.Error("error")
+	if got := messages(memory.getRecords()); len(got) != 4 || got[2] != "info2" || got[3] != "error" {
+		t.Error("再次触发后写入的日志错误", got)
+	}
+	if err := logger.Close(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,81 @@
+package flogtest_test
+
+import (
+	"fmt"
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/flogtest"
+	"strings"
+	"testing"
+)
+
+// 记录断言失败信息的 testing.TB
+type fakeTB struct {
+	testing.TB
+	failed []string
+}
+
+func (r *fakeTB) Helper() {}
+
+func (r *fakeTB) Errorf(format string, args ...interface{}) {
+	r.failed = append(r.failed, fmt.Sprintf(format, args...))
+}
+
+func (r *fakeTB) Fatalf(format string, args ...interface{}) {
+	r.failed = append(r.failed, fmt.Sprintf(format, args...))
+}
+
+func TestRecorder(t *testing.T) {
+	logger, recorder := flogtest.NewLogger(t, "test")
+	logger.With("OrderID", 100).Error("payment failed", "card declined")
+	logger.Info("payment retried")
+	logger.Debug("debug")
+	if recorder.Len() != 3 {
+		t.Fatalf("期待收集到 3 条日志，实际收集到 %d 条", recorder.Len())
+	}
+	record := recorder.RequireLogged(t, contract.LevelError, "payment failed")
+	if record.Context != "card declined" || record.Extra["OrderID"] != 100 {
+		t.Error("收集的日志内容错误", record)
+	}
+	recorder.AssertLogged(t, contract.LevelInfo, "retried")
+	recorder.AssertNotLogged(t, contract.LevelError, "retried")
+	if len(recorder.ByLevel(contract.LevelDebug)) != 1 || len(recorder.ByMessage("payment")) != 2 || len(recorder.ByExtra("OrderID")) != 1 {
+		t.Error("按条件查询日志错误")
+	}
+
+	//断言失败时输出已经收集的日志
+	fake := &fakeTB{TB: t}
+	if recorder.RequireLogged(fake, contract.LevelError, "refund") != nil || recorder.AssertLogged(fake, contract.LevelWarning, "payment") || recorder.AssertNotLogged(fake, contract.LevelInfo, "payment") {
+		t.Error("断言应该失败")
+	}
+	if len(fake.failed) != 3 || !strings.Contains(fake.failed[0], "test.error payment failed") {
+		t.Error("断言失败信息错误", fake.failed)
+	}
+
+	recorder.Reset()
+	if recorder.Len() != 0 || len(recorder.Records()) != 0 {
+		t.Error("清空收集的日志失败")
+	}
+}
+
+func TestTB(t *testing.T) {
+	var tb *flogtest.TB
+	t.Run("sub", func(t *testing.T) {
+		tb = flogtest.NewTB(t, contract.LevelInfo)
+		if tb.IsHandling(contract.LevelDebug) || !tb.IsHandling(contract.LevelError) {
+			t.Error("日志等级判断错误")
+		}
+		record := contract.AcquireRecord()
+		defer record.Release()
+		record.Level = contract.GetNameByLevel(contract.LevelInfo)
+		record.Message = "to t.Log"
+		tb.Handle(record)
+	})
+	//测试结束后收到的日志被丢弃，不会恐慌
+	record := contract.AcquireRecord()
+	defer record.Release()
+	record.Level = contract.GetNameByLevel(contract.LevelInfo)
+	record.Message = "after test"
+	if tb.Handle(record) {
+		t.Error("测试结束后收到的日志应该被丢弃")
+	}
+}
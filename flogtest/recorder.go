@@ -0,0 +1,201 @@
+// Package flogtest 日志的测试工具，在内存中收集日志并断言，或者将日志输出到 testing.TB
+package flogtest
+
+import (
+	"fmt"
+	"github.com/buexplain/go-flog"
+	"github.com/buexplain/go-flog/contract"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Recorder 在内存中收集日志的日志处理器，用于在测试中查询与断言日志
+// 收集的日志在调用 Reset 之前不会被回收复用，默认不阻止日志进入下一个日志处理器
+type Recorder struct {
+	//日志等级
+	level *contract.AtomicLevel
+	//是否阻止进入下一个日志处理器
+	bubble bool
+	lock   *sync.Mutex
+	//收集的日志
+	records []*contract.Record
+	//日志处理器名称
+	name string
+}
+
+func NewRecorder(level contract.Level) *Recorder {
+	tmp := new(Recorder)
+	tmp.level = contract.NewAtomicLevel(level)
+	tmp.bubble = false
+	tmp.lock = new(sync.Mutex)
+	tmp.name = "recorder"
+	return tmp
+}
+
+// NewLogger 创建一个将日志同时交给 Recorder 与 TB 日志处理器的日志收集器，测试结束时自动关闭
+func NewLogger(t testing.TB, channel string) (*flog.Logger, *Recorder) {
+	recorder := NewRecorder(contract.LevelDebug)
+	logger := flog.New(channel, recorder)
+	logger.PushHandler(NewTB(t, contract.LevelDebug))
+	t.Cleanup(func() {
+		_ = logger.Close()
+	})
+	return logger, recorder
+}
+
+// SetLevel 修改日志等级，可以在运行时安全调用
+func (r *Recorder) SetLevel(level contract.Level) {
+	r.level.SetLevel(level)
+}
+
+func (r *Recorder) GetLevel() contract.Level {
+	return r.level.GetLevel()
+}
+
+func (r *Recorder) SetBubble(bubble bool) *Recorder {
+	r.bubble = bubble
+	return r
+}
+
+func (r *Recorder) SetName(name string) *Recorder {
+	r.name = name
+	return r
+}
+
+func (r *Recorder) GetName() string {
+	return r.name
+}
+
+func (r *Recorder) Handle(record *contract.Record) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	//收集的日志在 Handle 返回后仍被持有，不能被回收
+	r.records = append(r.records, record.Retain())
+	return r.bubble
+}
+
+func (r *Recorder) IsHandling(level contract.Level) bool {
+	return r.level.IsHandling(level)
+}
+
+// Close 关闭后仍然可以查询已经收集的日志
+func (r *Recorder) Close() error {
+	return nil
+}
+
+// Reset 清空并回收已经收集的日志
+func (r *Recorder) Reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, v := range r.records {
+		v.Release()
+	}
+	r.records = nil
+}
+
+// Len 返回收集的日志数量
+func (r *Recorder) Len() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return len(r.records)
+}
+
+// Records 返回收集的日志的副本，按收集顺序排列
+func (r *Recorder) Records() []*contract.Record {
+	return r.Filter(func(record *contract.Record) bool {
+		return true
+	})
+}
+
+// Filter 返回满足条件的日志，按收集顺序排列
+func (r *Recorder) Filter(fn func(record *contract.Record) bool) []*contract.Record {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	tmp := make([]*contract.Record, 0, len(r.records))
+	for _, v := range r.records {
+		if fn(v) {
+			tmp = append(tmp, v)
+		}
+	}
+	return tmp
+}
+
+// ByLevel 返回指定等级的日志
+func (r *Recorder) ByLevel(level contract.Level) []*contract.Record {
+	name := contract.GetNameByLevel(level)
+	return r.Filter(func(record *contract.Record) bool {
+		return record.Level == name
+	})
+}
+
+// ByMessage 返回信息包含 substr 的日志
+func (r *Recorder) ByMessage(substr string) []*contract.Record {
+	return r.Filter(func(record *contract.Record) bool {
+		return strings.Contains(record.Message, substr)
+	})
+}
+
+// ByExtra 返回附加信息中有 key 的日志
+func (r *Recorder) ByExtra(key string) []*contract.Record {
+	return r.Filter(func(record *contract.Record) bool {
+		_, ok := record.Extra[key]
+		return ok
+	})
+}
+
+// Find 返回第一条指定等级并且信息包含 substr 的日志，没有则返回 nil
+func (r *Recorder) Find(level contract.Level, substr string) *contract.Record {
+	name := contract.GetNameByLevel(level)
+	records := r.Filter(func(record *contract.Record) bool {
+		return record.Level == name && strings.Contains(record.Message, substr)
+	})
+	if len(records) == 0 {
+		return nil
+	}
+	return records[0]
+}
+
+// 收集的日志的摘要，用于断言失败时的提示
+func (r *Recorder) summary() string {
+	records := r.Records()
+	if len(records) == 0 {
+		return "no records"
+	}
+	b := strings.Builder{}
+	_, _ = fmt.Fprintf(&b, "%d records:", len(records))
+	for _, v := range records {
+		_, _ = fmt.Fprintf(&b, "\n\t%s.%s %s", v.Channel, v.Level, v.Message)
+	}
+	return b.String()
+}
+
+// RequireLogged 断言收集到指定等级并且信息包含 substr 的日志，否则立即结束测试，返回找到的日志
+func (r *Recorder) RequireLogged(t testing.TB, level contract.Level, substr string) *contract.Record {
+	t.Helper()
+	record := r.Find(level, substr)
+	if record == nil {
+		t.Fatalf("flogtest: no %s record containing %q, %s", contract.GetNameByLevel(level), substr, r.summary())
+	}
+	return record
+}
+
+// AssertLogged 断言收集到指定等级并且信息包含 substr 的日志，否则标记测试失败
+func (r *Recorder) AssertLogged(t testing.TB, level contract.Level, substr string) bool {
+	t.Helper()
+	if r.Find(level, substr) == nil {
+		t.Errorf("flogtest: no %s record containing %q, %s", contract.GetNameByLevel(level), substr, r.summary())
+		return false
+	}
+	return true
+}
+
+// AssertNotLogged 断言没有收集到指定等级并且信息包含 substr 的日志，否则标记测试失败
+func (r *Recorder) AssertNotLogged(t testing.TB, level contract.Level, substr string) bool {
+	t.Helper()
+	if record := r.Find(level, substr); record != nil {
+		t.Errorf("flogtest: unexpected %s record %q", record.Level, record.Message)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,98 @@
+package flogtest
+
+import (
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/formatter"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TB 将日志输出到 testing.TB 的日志处理器，日志只在测试失败或者 go test -v 时显示
+// 测试结束后收到的日志会被丢弃，避免测试结束后调用 t.Log 引发恐慌
+type TB struct {
+	//日志等级
+	level *contract.AtomicLevel
+	//日志格式化处理器
+	formatter contract.Formatter
+	//是否阻止进入下一个日志处理器
+	bubble bool
+	t      testing.TB
+	lock   *sync.Mutex
+	//测试是否已经结束
+	done bool
+	//日志处理器名称
+	name string
+}
+
+// NewTB 创建输出到 t 的日志处理器，默认使用行格式化处理器
+func NewTB(t testing.TB, level contract.Level) *TB {
+	tmp := new(TB)
+	tmp.level = contract.NewAtomicLevel(level)
+	tmp.formatter = formatter.NewLine()
+	tmp.bubble = false
+	tmp.t = t
+	tmp.lock = new(sync.Mutex)
+	tmp.name = "tb"
+	t.Cleanup(func() {
+		tmp.lock.Lock()
+		defer tmp.lock.Unlock()
+		tmp.done = true
+	})
+	return tmp
+}
+
+func (r *TB) SetFormatter(formatter contract.Formatter) *TB {
+	r.formatter = formatter
+	return r
+}
+
+// SetLevel 修改日志等级，可以在运行时安全调用
+func (r *TB) SetLevel(level contract.Level) {
+	r.level.SetLevel(level)
+}
+
+func (r *TB) GetLevel() contract.Level {
+	return r.level.GetLevel()
+}
+
+func (r *TB) SetBubble(bubble bool) *TB {
+	r.bubble = bubble
+	return r
+}
+
+func (r *TB) SetName(name string) *TB {
+	r.name = name
+	return r
+}
+
+func (r *TB) GetName() string {
+	return r.name
+}
+
+func (r *TB) Handle(record *contract.Record) bool {
+	buf, err := r.formatter.ToBuffer(record)
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.done {
+		if err == nil {
+			contract.ReleaseBuffer(buf)
+		}
+		return false
+	}
+	if err != nil {
+		r.t.Log("flogtest: format record failed:", err)
+		return false
+	}
+	r.t.Log(strings.TrimSuffix(buf.String(), "\n"))
+	contract.ReleaseBuffer(buf)
+	return r.bubble
+}
+
+func (r *TB) IsHandling(level contract.Level) bool {
+	return r.level.IsHandling(level)
+}
+
+func (r *TB) Close() error {
+	return nil
+}
@@ -0,0 +1,320 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/buexplain/go-flog/contract"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+const hex = "0123456789abcdef"
+
+// 将字符串按json规则转义后写入缓冲区，转义规则与 encoding/json 保持一致
+func appendJSONString(buf *bytes.Buffer, s string, escapeHTML bool) {
+	buf.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= ' ' && b != '"' && b != '\\' && (!escapeHTML || (b != '<' && b != '>' && b != '&')) {
+				i++
+				continue
+			}
+			buf.WriteString(s[start:i])
+			switch b {
+			case '\\', '"':
+				buf.WriteByte('\\')
+				buf.WriteByte(b)
+			case '\n':
+				buf.WriteString(`\n`)
+			case '\r':
+				buf.WriteString(`\r`)
+			case '\t':
+				buf.WriteString(`\t`)
+			default:
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hex[b>>4])
+				buf.WriteByte(hex[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		c, size := utf8.DecodeRuneInString(s[i:])
+		if c == utf8.RuneError && size == 1 {
+			buf.WriteString(s[start:i])
+			buf.WriteString("\ufffd")
+			i += size
+			start = i
+			continue
+		}
+		if c == '\u2028' || c == '\u2029' {
+			buf.WriteString(s[start:i])
+			buf.WriteString(`\u202`)
+			buf.WriteByte(hex[c&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf.WriteString(s[start:])
+	buf.WriteByte('"')
+}
+
+// 将浮点数按json规则写入缓冲区，NaN与Inf无法用json表示，写为字符串
+func appendJSONFloat(buf *bytes.Buffer, f float64, bits int) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		buf.WriteByte('"')
+		buf.WriteString(strconv.FormatFloat(f, 'g', -1, bits))
+		buf.WriteByte('"')
+		return
+	}
+	var scratch [64]byte
+	abs := math.Abs(f)
+	fmtByte := byte('f')
+	if abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			fmtByte = 'e'
+		}
+	}
+	b := strconv.AppendFloat(scratch[:0], f, fmtByte, -1, bits)
+	if fmtByte == 'e' {
+		//将 e-09 处理为 e-9，与 encoding/json 保持一致
+		n := len(b)
+		if n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	buf.Write(b)
+}
+
+// 将任意值按json规则写入缓冲区，常见类型直接编码，其余类型退化为 encoding/json
+func appendJSONValue(buf *bytes.Buffer, v interface{}, escapeHTML bool) error {
+	var scratch [64]byte
+	switch val := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case string:
+		appendJSONString(buf, val, escapeHTML)
+	case bool:
+		buf.Write(strconv.AppendBool(scratch[:0], val))
+	case int:
+		buf.Write(strconv.AppendInt(scratch[:0], int64(val), 10))
+	case int8:
+		buf.Write(strconv.AppendInt(scratch[:0], int64(val), 10))
+	case int16:
+		buf.Write(strconv.AppendInt(scratch[:0], int64(val), 10))
+	case int32:
+		buf.Write(strconv.AppendInt(scratch[:0], int64(val), 10))
+	case int64:
+		buf.Write(strconv.AppendInt(scratch[:0], val, 10))
+	case uint:
+		buf.Write(strconv.AppendUint(scratch[:0], uint64(val), 10))
+	case uint8:
+		buf.Write(strconv.AppendUint(scratch[:0], uint64(val), 10))
+	case uint16:
+		buf.Write(strconv.AppendUint(scratch[:0], uint64(val), 10))
+	case uint32:
+		buf.Write(strconv.AppendUint(scratch[:0], uint64(val), 10))
+	case uint64:
+		buf.Write(strconv.AppendUint(scratch[:0], val, 10))
+	case float32:
+		appendJSONFloat(buf, float64(val), 32)
+	case float64:
+		appendJSONFloat(buf, val, 64)
+	case time.Duration:
+		buf.Write(strconv.AppendInt(scratch[:0], int64(val), 10))
+	case time.Time:
+		buf.WriteByte('"')
+		buf.Write(val.AppendFormat(scratch[:0], time.RFC3339Nano))
+		buf.WriteByte('"')
+	case contract.Field:
+		return appendJSONFieldValue(buf, val, escapeHTML)
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, item := range val {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := appendJSONValue(buf, item, escapeHTML); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		return appendJSONMap(buf, val, escapeHTML)
+	case *contract.ErrorDetail:
+		appendJSONError(buf, val, escapeHTML)
+	case json.Marshaler:
+		return appendJSONReflect(buf, v, escapeHTML)
+	case error:
+		//没有经过日志收集器转换的错误，比如直接构造的日志
+		appendJSONError(buf, contract.NewErrorDetail(val, ""), escapeHTML)
+	default:
+		return appendJSONReflect(buf, v, escapeHTML)
+	}
+	return nil
+}
+
+// 将结构化的错误写为json对象，省略空的错误链与调用栈
+func appendJSONError(buf *bytes.Buffer, detail *contract.ErrorDetail, escapeHTML bool) {
+	buf.WriteString(`{"Message":`)
+	appendJSONString(buf, detail.Message, escapeHTML)
+	buf.WriteString(`,"Type":`)
+	appendJSONString(buf, detail.Type, escapeHTML)
+	if len(detail.Causes) > 0 {
+		buf.WriteString(`,"Causes":[`)
+		for i, cause := range detail.Causes {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			appendJSONError(buf, cause, escapeHTML)
+		}
+		buf.WriteByte(']')
+	}
+	if detail.Stack != "" {
+		buf.WriteString(`,"Stack":`)
+		appendJSONString(buf, detail.Stack, escapeHTML)
+	}
+	buf.WriteByte('}')
+}
+
+// 通过 encoding/json 编码无法直接识别的值
+func appendJSONReflect(buf *bytes.Buffer, v interface{}, escapeHTML bool) error {
+	start := buf.Len()
+	e := json.NewEncoder(buf)
+	e.SetEscapeHTML(escapeHTML)
+	if err := e.Encode(v); err != nil {
+		buf.Truncate(start)
+		return err
+	}
+	//去掉 Encode 追加的换行符
+	buf.Truncate(buf.Len() - 1)
+	return nil
+}
+
+// 按键名排序后写入map，与 encoding/json 保持一致
+func appendJSONMap(buf *bytes.Buffer, m map[string]interface{}, escapeHTML bool) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	buf.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		appendJSONString(buf, k, escapeHTML)
+		buf.WriteByte(':')
+		if err := appendJSONValue(buf, m[k], escapeHTML); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// 按字段类型写入结构化字段的值
+func appendJSONFieldValue(buf *bytes.Buffer, field contract.Field, escapeHTML bool) error {
+	var scratch [64]byte
+	switch field.Type {
+	case contract.FieldTypeString:
+		appendJSONString(buf, field.String, escapeHTML)
+	case contract.FieldTypeInt64, contract.FieldTypeDuration:
+		buf.Write(strconv.AppendInt(scratch[:0], field.Integer, 10))
+	case contract.FieldTypeUint64:
+		buf.Write(strconv.AppendUint(scratch[:0], uint64(field.Integer), 10))
+	case contract.FieldTypeFloat64:
+		appendJSONFloat(buf, math.Float64frombits(uint64(field.Integer)), 64)
+	case contract.FieldTypeBool:
+		buf.Write(strconv.AppendBool(scratch[:0], field.Integer == 1))
+	case contract.FieldTypeTime:
+		buf.WriteByte('"')
+		buf.Write(field.Time().AppendFormat(scratch[:0], time.RFC3339Nano))
+		buf.WriteByte('"')
+	case contract.FieldTypeError:
+		return appendJSONValue(buf, field.Interface, escapeHTML)
+	case contract.FieldTypeAny:
+		return appendJSONValue(buf, field.Interface, escapeHTML)
+	default:
+		buf.WriteString("null")
+	}
+	return nil
+}
+
+// 将结构化字段集合写为json对象
+func appendJSONFields(buf *bytes.Buffer, fields []contract.Field, escapeHTML bool) error {
+	buf.WriteByte('{')
+	for i, field := range fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		appendJSONString(buf, field.Key, escapeHTML)
+		buf.WriteByte(':')
+		if err := appendJSONFieldValue(buf, field, escapeHTML); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// 将任意值按 %+v 写入缓冲区，常见类型直接写入，避免 fmt 的反射开销
+func appendText(buf *bytes.Buffer, v interface{}, timeFormat string) {
+	var scratch [64]byte
+	switch val := v.(type) {
+	case string:
+		buf.WriteString(val)
+	case bool:
+		buf.Write(strconv.AppendBool(scratch[:0], val))
+	case int:
+		buf.Write(strconv.AppendInt(scratch[:0], int64(val), 10))
+	case int64:
+		buf.Write(strconv.AppendInt(scratch[:0], val, 10))
+	case uint64:
+		buf.Write(strconv.AppendUint(scratch[:0], val, 10))
+	case float64:
+		buf.Write(strconv.AppendFloat(scratch[:0], val, 'g', -1, 64))
+	case time.Duration:
+		buf.WriteString(val.String())
+	case contract.Field:
+		appendFieldText(buf, val, timeFormat)
+	default:
+		_, _ = fmt.Fprintf(buf, "%+v", v)
+	}
+}
+
+// 按字段类型写入结构化字段的文本值
+func appendFieldText(buf *bytes.Buffer, field contract.Field, timeFormat string) {
+	var scratch [64]byte
+	switch field.Type {
+	case contract.FieldTypeString:
+		buf.WriteString(field.String)
+	case contract.FieldTypeInt64:
+		buf.Write(strconv.AppendInt(scratch[:0], field.Integer, 10))
+	case contract.FieldTypeUint64:
+		buf.Write(strconv.AppendUint(scratch[:0], uint64(field.Integer), 10))
+	case contract.FieldTypeFloat64:
+		buf.Write(strconv.AppendFloat(scratch[:0], math.Float64frombits(uint64(field.Integer)), 'g', -1, 64))
+	case contract.FieldTypeBool:
+		buf.Write(strconv.AppendBool(scratch[:0], field.Integer == 1))
+	case contract.FieldTypeDuration:
+		buf.WriteString(time.Duration(field.Integer).String())
+	case contract.FieldTypeTime:
+		buf.Write(field.Time().AppendFormat(scratch[:0], timeFormat))
+	case contract.FieldTypeError:
+		//结构化的错误按 %+v 输出错误类型与错误链
+		appendText(buf, field.Interface, timeFormat)
+	case contract.FieldTypeAny:
+		appendText(buf, field.Interface, timeFormat)
+	default:
+		buf.WriteString("<nil>")
+	}
+}
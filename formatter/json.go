@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/internal/metrics"
 	"io"
+	"time"
 )
 
 // JSON json化日志结构体
@@ -31,14 +33,57 @@ func (r *JSON) SetEscapeHTML(on bool) *JSON {
 }
 
 func (r *JSON) ToBuffer(record *contract.Record) (buf *bytes.Buffer, err error) {
-	buf = &bytes.Buffer{}
-	e := json.NewEncoder(buf)
-	e.SetIndent(r.prefix, r.indent)
-	e.SetEscapeHTML(r.escapeHTML)
-	if err := e.Encode(record); err != nil {
+	buf = contract.AcquireBuffer()
+	if err = r.encode(buf, record); err != nil {
+		contract.ReleaseBuffer(buf)
+		metrics.FormatErrors.Add("json", 0, 1)
 		return nil, err
 	}
-	return buf, nil
+	if r.prefix == "" && r.indent == "" {
+		return buf, nil
+	}
+	//按设置的前缀与缩进美化json
+	indented := contract.AcquireBuffer()
+	err = json.Indent(indented, buf.Bytes(), r.prefix, r.indent)
+	contract.ReleaseBuffer(buf)
+	if err != nil {
+		contract.ReleaseBuffer(indented)
+		metrics.FormatErrors.Add("json", 0, 1)
+		return nil, err
+	}
+	//json.Indent 保留了原有的结尾换行符
+	return indented, nil
+}
+
+// 直接编码日志结构体，结构化字段与常见类型无需反射，输出结构与 encoding/json 编码 contract.Record 一致
+func (r *JSON) encode(buf *bytes.Buffer, record *contract.Record) error {
+	var scratch [64]byte
+	buf.WriteString(`{"Channel":`)
+	appendJSONString(buf, record.Channel, r.escapeHTML)
+	buf.WriteString(`,"Level":`)
+	appendJSONString(buf, record.Level, r.escapeHTML)
+	buf.WriteString(`,"Message":`)
+	appendJSONString(buf, record.Message, r.escapeHTML)
+	buf.WriteString(`,"Context":`)
+	if err := appendJSONValue(buf, record.Context, r.escapeHTML); err != nil {
+		return err
+	}
+	if len(record.Fields) > 0 {
+		buf.WriteString(`,"Fields":`)
+		if err := appendJSONFields(buf, record.Fields, r.escapeHTML); err != nil {
+			return err
+		}
+	}
+	buf.WriteString(`,"Extra":`)
+	if record.Extra == nil {
+		buf.WriteString("null")
+	} else if err := appendJSONMap(buf, record.Extra, r.escapeHTML); err != nil {
+		return err
+	}
+	buf.WriteString(`,"Time":"`)
+	buf.Write(record.Time.AppendFormat(scratch[:0], time.RFC3339Nano))
+	buf.WriteString("\"}\n")
+	return nil
 }
 
 func (r *JSON) ToWriter(w io.Writer, record *contract.Record) (written int64, err error) {
@@ -49,6 +94,7 @@ func (r *JSON) ToWriter(w io.Writer, record *contract.Record) (written int64, er
 	}
 	var n int
 	n , err = w.Write(buf.Bytes())
+	contract.ReleaseBuffer(buf)
 	if err != nil {
 		return 0, err
 	}
@@ -2,9 +2,15 @@ package formatter_test
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/buexplain/go-flog"
 	"github.com/buexplain/go-flog/contract"
 	"github.com/buexplain/go-flog/formatter"
+	"math"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestJSON(t *testing.T) {
@@ -37,3 +43,123 @@ func TestJSON(t *testing.T) {
 	}
 	t.Log(i, buf.String())
 }
+
+// 测试直接编码的结果与 encoding/json 的结果一致
+func TestJSONCompatible(t *testing.T) {
+	record := contract.NewRecord()
+	record.Extra["extraA"] = "<extra>\n\"& "
+	record.Extra["extraB"] = 100
+	record.Extra["extraC"] = 1e-7
+	record.Extra["extraD"] = []interface{}{1.5, true, nil, "a"}
+	record.Extra["extraE"] = struct {
+		Name string
+		Age  uint8
+	}{
+		Name: "西门吹雪",
+		Age:  108,
+	}
+	record.Channel = "channel"
+	record.Message = "message\x01\xff"
+	record.Context = map[string]interface{}{"b": int64(2), "a": uint32(1)}
+	record.Level = contract.GetNameByLevel(contract.LevelDebug)
+	for _, escapeHTML := range []bool{true, false} {
+		expected := &bytes.Buffer{}
+		e := json.NewEncoder(expected)
+		e.SetEscapeHTML(escapeHTML)
+		if err := e.Encode(struct {
+			Channel string
+			Level   string
+			Message string
+			Context interface{}
+			Extra   map[string]interface{}
+			Time    time.Time
+		}{record.Channel, record.Level, record.Message, record.Context, record.Extra, record.Time}); err != nil {
+			t.Error("json编码失败：", err)
+			return
+		}
+		buf, err := formatter.NewJSON().SetEscapeHTML(escapeHTML).ToBuffer(record)
+		if err != nil {
+			t.Error("json格式化失败：", err)
+			return
+		}
+		if buf.String() != expected.String() {
+			t.Errorf("json格式化的结果与 encoding/json 不一致\n期待：%s实际：%s", expected.String(), buf.String())
+		}
+	}
+}
+
+// 测试结构化字段的编码
+func TestJSONFields(t *testing.T) {
+	record := contract.NewRecord()
+	record.Level = contract.GetNameByLevel(contract.LevelInfo)
+	record.Message = "message"
+	record.Fields = []contract.Field{
+		flog.String("string", "value"),
+		flog.Int("int", -1),
+		flog.Uint64("uint64", 18446744073709551615),
+		flog.Float64("float64", 1.25),
+		flog.Float64("nan", math.NaN()),
+		flog.Bool("bool", true),
+		flog.Duration("duration", time.Second),
+		flog.Time("time", time.Date(2021, 1, 2, 3, 4, 5, 6, time.UTC)),
+		flog.Time("zeroTime", time.Time{}),
+		flog.Time("farTime", time.Date(3000, 1, 2, 3, 4, 5, 0, time.UTC)),
+		flog.Err(errors.New("failed")),
+		flog.Any("any", struct{ A int }{A: 1}),
+		flog.Any("nil", nil),
+	}
+	//零值时间与超出纳秒时间戳范围的时间原样保存
+	if !record.Fields[8].Time().IsZero() || record.Fields[9].Time().Year() != 3000 {
+		t.Error("时间字段保存错误", record.Fields[8].Time(), record.Fields[9].Time())
+	}
+	buf, err := formatter.NewJSON().SetIndent("", "  ").ToBuffer(record)
+	if err != nil {
+		t.Error("json格式化失败：", err)
+		return
+	}
+	result := struct {
+		Fields map[string]interface{}
+	}{}
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Error("json格式化的结果无法解析：", err, buf.String())
+		return
+	}
+	expected := map[string]interface{}{
+		"string":   "value",
+		"int":      float64(-1),
+		"uint64":   float64(18446744073709551615),
+		"float64":  1.25,
+		"nan":      "NaN",
+		"bool":     true,
+		"duration": float64(time.Second),
+		"time":     "2021-01-02T03:04:05.000000006Z",
+		"zeroTime": "0001-01-01T00:00:00Z",
+		"farTime":  "3000-01-02T03:04:05Z",
+		"error":    map[string]interface{}{"Message": "failed", "Type": "*errors.errorString"},
+		"any":      map[string]interface{}{"A": float64(1)},
+		"nil":      nil,
+	}
+	for k, v := range expected {
+		if !reflect.DeepEqual(result.Fields[k], v) {
+			t.Errorf("结构化字段 %s 编码错误，期待 %v 实际 %v", k, v, result.Fields[k])
+		}
+	}
+	t.Log(buf.String())
+}
+
+func TestJSONIndent(t *testing.T) {
+	record := contract.NewRecord()
+	record.Channel = "test"
+	record.Level = "info"
+	record.Message = "message"
+	record.Time = time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	buf, err := formatter.NewJSON().SetIndent("", "  ").ToBuffer(record)
+	if err != nil {
+		t.Fatal("json格式化失败：", err)
+	}
+	//每条日志只以一个换行符结尾
+	expected := "{\n  \"Channel\": \"test\",\n  \"Level\": \"info\",\n  \"Message\": \"message\",\n  \"Context\": null,\n  \"Extra\": {},\n  \"Time\": \"2021-01-02T03:04:05Z\"\n}\n"
+	if buf.String() != expected {
+		t.Errorf("json缩进格式化的结果错误：%q", buf.String())
+	}
+}
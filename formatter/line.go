@@ -2,7 +2,6 @@ package formatter
 
 import (
 	"bytes"
-	"fmt"
 	"github.com/buexplain/go-flog/contract"
 	"io"
 	"time"
@@ -27,9 +26,10 @@ func (r *Line) SetTimeFormat(format string) *Line {
 }
 
 func (r *Line) format(record *contract.Record) (buf *bytes.Buffer, err error) {
-	buf = &bytes.Buffer{}
+	var scratch [64]byte
+	buf = contract.AcquireBuffer()
 	buf.WriteByte('[')
-	buf.WriteString(record.Time.Format(r.timeFormat))
+	buf.Write(record.Time.AppendFormat(scratch[:0], r.timeFormat))
 	buf.WriteByte(']')
 	buf.WriteByte(' ')
 	if len(record.Channel) > 0 {
@@ -40,19 +40,30 @@ func (r *Line) format(record *contract.Record) (buf *bytes.Buffer, err error) {
 	buf.WriteByte(' ')
 	buf.WriteString(record.Message)
 	if record.Context != nil {
-		_, _ = fmt.Fprintf(buf, " %+v", record.Context)
+		buf.WriteByte(' ')
+		appendText(buf, record.Context, r.timeFormat)
 	}
-	if record.Extra != nil {
-		l := len(record.Extra)
-		i := 1
-		for k, v := range record.Extra {
-			if i == l {
-				_, _ = fmt.Fprintf(buf, " %s: %+v", k, v)
-			}else {
-				_, _ = fmt.Fprintf(buf, " %s: %+v,", k, v)
-			}
-			i++
+	l := len(record.Fields) + len(record.Extra)
+	i := 1
+	for _, field := range record.Fields {
+		buf.WriteByte(' ')
+		buf.WriteString(field.Key)
+		buf.WriteString(": ")
+		appendFieldText(buf, field, r.timeFormat)
+		if i != l {
+			buf.WriteByte(',')
 		}
+		i++
+	}
+	for k, v := range record.Extra {
+		buf.WriteByte(' ')
+		buf.WriteString(k)
+		buf.WriteString(": ")
+		appendText(buf, v, r.timeFormat)
+		if i != l {
+			buf.WriteByte(',')
+		}
+		i++
 	}
 	buf.WriteByte('\n')
 	return
@@ -70,6 +81,7 @@ func (r *Line) ToWriter(w io.Writer, record *contract.Record) (written int64, er
 	}
 	var n int
 	n , err = w.Write(buf.Bytes())
+	contract.ReleaseBuffer(buf)
 	if err != nil {
 		return 0, err
 	}
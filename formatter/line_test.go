@@ -2,9 +2,13 @@ package formatter_test
 
 import (
 	"bytes"
+	"errors"
+	"github.com/buexplain/go-flog"
 	"github.com/buexplain/go-flog/contract"
 	"github.com/buexplain/go-flog/formatter"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLine(t *testing.T) {
@@ -42,3 +46,28 @@ func TestLine(t *testing.T) {
 	}
 	t.Log(i, buf.String())
 }
+
+// 测试结构化字段的行化
+func TestLineFields(t *testing.T) {
+	record := contract.NewRecord()
+	record.Message = "message"
+	record.Level = contract.GetNameByLevel(contract.LevelInfo)
+	record.Fields = []contract.Field{
+		flog.String("string", "value"),
+		flog.Int("int", -1),
+		flog.Float64("float64", 1.25),
+		flog.Bool("bool", true),
+		flog.Duration("duration", time.Second),
+		flog.Err(errors.New("failed")),
+	}
+	record.Extra["extra"] = 1
+	buf, err := formatter.NewLine().ToBuffer(record)
+	if err != nil {
+		t.Error("line格式化失败：", err.Error())
+		return
+	}
+	expected := " info message string: value, int: -1, float64: 1.25, bool: true, duration: 1s, error: failed, extra: 1\n"
+	if !strings.HasSuffix(buf.String(), expected) {
+		t.Errorf("line格式化结构化字段错误\n期待：%s实际：%s", expected, buf.String())
+	}
+}
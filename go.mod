@@ -1,3 +1,3 @@
 module github.com/buexplain/go-flog
 
-go 1.17
+go 1.21
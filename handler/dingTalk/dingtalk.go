@@ -1,27 +1,36 @@
 package dingtalk
 
 import (
+	"context"
 	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/internal/metrics"
+	"github.com/buexplain/go-flog/internal/report"
 	"sync"
 )
 
 type DingTalk struct {
-	level     contract.Level
+	level     *contract.AtomicLevel
 	robotCh   chan *Robot
 	robots    []*Robot
 	writeLock *sync.Mutex
 	compress  bool
 	compressed map[string]byte
 	timestamp int64
+	//日志处理器名称
+	name string
+	//内部错误处理器
+	errorHandler contract.AtomicErrorHandler
 }
 
 func New(level contract.Level, robots []*Robot, compress bool) *DingTalk {
 	tmp := new(DingTalk)
-	tmp.level = level
+	tmp.level = contract.NewAtomicLevel(level)
 	tmp.robotCh = make(chan *Robot, len(robots))
 	tmp.robots = make([]*Robot, 0, len(robots))
 	tmp.writeLock = new(sync.Mutex)
+	tmp.name = "dingtalk"
 	for _, robot := range robots {
+		robot.handleError = tmp.handleError
 		tmp.robotCh <- robot
 		tmp.robots = append(tmp.robots, robot)
 	}
@@ -45,6 +54,7 @@ func (r *DingTalk) Handle(record *contract.Record) bool {
 			r.timestamp = t
 		}else {
 			if _, ok := r.compressed[record.Message]; ok {
+				metrics.DingTalkDeduplicated.Add(r.name, 0, 1)
 				//强制进入下一个日志处理器
 				return true
 			}
@@ -60,7 +70,60 @@ func (r *DingTalk) Handle(record *contract.Record) bool {
 
 // IsHandling 判断当前处理器是否可以处理日志
 func (r *DingTalk) IsHandling(level contract.Level) bool {
-	return level <= r.level
+	return r.level.IsHandling(level)
+}
+
+// SetLevel 修改日志等级，可以在运行时安全调用
+func (r *DingTalk) SetLevel(level contract.Level) {
+	r.level.SetLevel(level)
+}
+
+func (r *DingTalk) GetLevel() contract.Level {
+	return r.level.GetLevel()
+}
+
+// SetAtomicLevel 设置与其它日志处理器共享的日志等级，需要在写入日志之前调用
+func (r *DingTalk) SetAtomicLevel(level *contract.AtomicLevel) *DingTalk {
+	if level != nil {
+		r.level = level
+	}
+	return r
+}
+
+func (r *DingTalk) SetName(name string) *DingTalk {
+	r.name = name
+	return r
+}
+
+func (r *DingTalk) GetName() string {
+	return r.name
+}
+
+// SetErrorHandler 设置内部错误处理器，可以在运行时安全调用
+func (r *DingTalk) SetErrorHandler(handler contract.ErrorHandler) {
+	r.errorHandler.SetErrorHandler(handler)
+}
+
+func (r *DingTalk) GetErrorHandler() contract.ErrorHandler {
+	return r.errorHandler.GetErrorHandler()
+}
+
+// 处理钉钉群机器人的内部错误
+func (r *DingTalk) handleError(kind contract.ErrorKind, record *contract.Record, err error) {
+	report.Error(r.errorHandler.GetErrorHandler(), &contract.Error{Handler: r.name, Kind: kind, Record: record, Err: err})
+}
+
+// Flush 等待各个钉钉群机器人队列中的消息发送完毕
+func (r *DingTalk) Flush(ctx context.Context) error {
+	r.writeLock.Lock()
+	robots := r.robots
+	r.writeLock.Unlock()
+	for _, robot := range robots {
+		if err := robot.flush(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Close 关闭日志处理器
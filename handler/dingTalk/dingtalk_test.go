@@ -57,9 +57,10 @@ func TestDingTalk(t *testing.T) {
 			}{C: 100}}
 			level := []contract.Level{contract.LevelDebug, contract.LevelInfo, contract.LevelError, contract.LevelAlert}
 			for _, v := range level {
-				tmp := &(*record)
+				//复制一份日志，机器人队列持有的是日志指针
+				tmp := *record
 				tmp.Level = contract.GetNameByLevel(v)
-				dTalk.Handle(tmp)
+				dTalk.Handle(&tmp)
 			}
 		}()
 	}
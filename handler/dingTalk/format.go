@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/internal/metrics"
 	"io"
 	"strings"
 	"time"
@@ -70,16 +71,21 @@ func (r *FormatText) format(record *contract.Record) (buf *bytes.Buffer, err err
 	if record.Context != nil {
 		_, _ = fmt.Fprintf(s, "\n%+v", record.Context)
 	}
+	for _, field := range record.Fields {
+		_, _ = fmt.Fprintf(s, "\n%s: %+v", field.Key, field.Value())
+	}
 	if record.Extra != nil && len(record.Extra) > 0 {
 		for k, v := range record.Extra {
 			_, _ = fmt.Fprintf(s, "\n%s: %+v", k, v)
 		}
 	}
 	body.Text.Content = s.String()
-	buf = bytes.NewBuffer(nil)
+	buf = contract.AcquireBuffer()
 	e := json.NewEncoder(buf)
 	err = e.Encode(body)
 	if err != nil {
+		contract.ReleaseBuffer(buf)
+		metrics.FormatErrors.Add("dingtalk_text", 0, 1)
 		return nil, err
 	}
 	return buf, nil
@@ -97,6 +103,7 @@ func (r *FormatText) ToWriter(w io.Writer, record *contract.Record) (written int
 	}
 	var n int
 	n , err = w.Write(buf.Bytes())
+	contract.ReleaseBuffer(buf)
 	if err != nil {
 		return 0, err
 	}
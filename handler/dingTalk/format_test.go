@@ -2,8 +2,11 @@ package dingtalk_test
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"github.com/buexplain/go-flog/contract"
 	dingtalk "github.com/buexplain/go-flog/handler/dingTalk"
+	"strings"
 	"sync"
 	"testing"
 )
@@ -55,3 +58,17 @@ func TestFormatText(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestFormatTextError(t *testing.T) {
+	record := contract.NewRecord()
+	record.Level = contract.GetNameByLevel(contract.LevelError)
+	record.Message = "message"
+	record.Context = contract.NewErrorDetail(fmt.Errorf("wrap: %w", errors.New("cause")), "")
+	buf, err := dingtalk.NewFormatText().ToBuffer(record)
+	if err != nil {
+		t.Fatal("钉钉text格式化失败", err)
+	}
+	if !strings.Contains(buf.String(), `wrap: cause (*fmt.wrapError) \u003c- cause (*errors.errorString)`) {
+		t.Error("钉钉text格式化的错误不完整", buf.String())
+	}
+}
@@ -2,25 +2,30 @@ package dingtalk
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
 	"github.com/buexplain/go-flog/contract"
-	"io"
-	libLog "log"
+	"github.com/buexplain/go-flog/internal/report"
 	"net/http"
 	"net/url"
 	"strconv"
+	"sync/atomic"
 	"time"
 )
 
 type Robot struct {
+	//等待发送与正在发送的消息数量，放在结构体开头，保证32位平台上原子操作的内存对齐
+	pending   int64
 	url       string
 	secret    []byte
 	formatter contract.Formatter
-	recordCh  chan contract.Record
+	recordCh  chan *contract.Record
 	closed    chan struct{}
+	//内部错误的处理函数，由所属的钉钉日志处理器设置
+	handleError func(kind contract.ErrorKind, record *contract.Record, err error)
 }
 
 func NewRobot(url string, secret string, formatter contract.Formatter, capacity int) *Robot {
@@ -32,8 +37,11 @@ func NewRobot(url string, secret string, formatter contract.Formatter, capacity
 		tmp.secret = []byte(secret)
 	}
 	tmp.formatter = formatter
-	tmp.recordCh = make(chan contract.Record, capacity)
+	tmp.recordCh = make(chan *contract.Record, capacity)
 	tmp.closed = make(chan struct{})
+	tmp.handleError = func(kind contract.ErrorKind, record *contract.Record, err error) {
+		report.Error(nil, &contract.Error{Handler: "dingtalk", Kind: kind, Record: record, Err: err})
+	}
 	tmp.gof()
 	return tmp
 }
@@ -49,6 +57,7 @@ func (r *Robot) gof() {
 		defer tick.Stop()
 		defer func() {
 			if re := recover(); re != nil {
+				r.handleError(contract.ErrorKindPanic, nil, report.Panic(re))
 				//如果异常退出，则间隔一段时间后重启动一条协程
 				<-time.After(10 * time.Second)
 				r.gof()
@@ -56,13 +65,7 @@ func (r *Robot) gof() {
 				close(r.recordCh)
 			}
 		}()
-		var buf *bytes.Buffer
-		var err error
-		var req *http.Request
 		for {
-			buf = nil
-			err = nil
-			req = nil
 			select {
 			case <-r.closed:
 				return
@@ -71,41 +74,74 @@ func (r *Robot) gof() {
 				case <-r.closed:
 					return
 				case record := <-r.recordCh:
-					buf, err = r.formatter.ToBuffer(&record)
-					if err != nil {
-						libLog.Println(err)
-						break
-					}
-					req, err = http.NewRequest(http.MethodPost, r.makeURL(), io.NopCloser(buf))
-					if err != nil {
-						libLog.Println(err)
-						break
-					}
-					req.Header.Add("Content-Type", "application/json;charset=utf-8")
-					client := http.Client{Timeout: time.Second * 5}
-					var resp *http.Response
-					if resp, err = client.Do(req); err != nil {
-						if e, ok := err.(*url.Error); !ok || !e.Timeout() {
-							libLog.Println(err)
-						}
-					} else {
-						_ = resp.Body.Close()
-					}
+					r.post(record)
 				}
 			}
 		}
 	}()
 }
 
+// 发送消息到钉钉群机器人
+func (r *Robot) post(record *contract.Record) {
+	defer atomic.AddInt64(&r.pending, -1)
+	defer record.Release()
+	buf, err := r.formatter.ToBuffer(record)
+	if err != nil {
+		r.handleError(contract.ErrorKindFormat, record, err)
+		return
+	}
+	var req *http.Request
+	req, err = http.NewRequest(http.MethodPost, r.makeURL(), contract.NewBufferReader(buf))
+	if err != nil {
+		contract.ReleaseBuffer(buf)
+		r.handleError(contract.ErrorKindRequest, record, err)
+		return
+	}
+	req.Header.Add("Content-Type", "application/json;charset=utf-8")
+	client := http.Client{Timeout: time.Second * 5}
+	var resp *http.Response
+	if resp, err = client.Do(req); err != nil {
+		if e, ok := err.(*url.Error); ok && e.Timeout() {
+			r.handleError(contract.ErrorKindTimeout, record, err)
+		} else {
+			r.handleError(contract.ErrorKindRequest, record, err)
+		}
+	} else {
+		_ = resp.Body.Close()
+	}
+}
+
+// 等待队列中的消息发送完毕
+func (r *Robot) flush(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for atomic.LoadInt64(&r.pending) > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-r.closed:
+			return nil
+		case <-ticker.C:
+			break
+		}
+	}
+	return nil
+}
+
 func (r *Robot) send(record *contract.Record) bool {
 	select {
 	case <-r.closed:
 		return false
 	default:
+		atomic.AddInt64(&r.pending, 1)
+		//日志在发送队列中等待，Handle 返回后仍需持有
+		record.Retain()
 		select {
-		case r.recordCh <- *record:
+		case r.recordCh <- record:
 			return true
 		default:
+			record.Release()
+			atomic.AddInt64(&r.pending, -1)
 			return false
 		}
 	}
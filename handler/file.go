@@ -3,9 +3,12 @@ package handler
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/internal/metrics"
+	"github.com/buexplain/go-flog/internal/report"
 	"io"
 	"io/fs"
 	libLog "log"
@@ -21,7 +24,7 @@ import (
 // File 文件日志处理器
 type File struct {
 	//日志等级
-	level contract.Level
+	level *contract.AtomicLevel
 	//日志格式化处理器
 	formatter contract.Formatter
 	//是否阻止进入下一个日志处理器
@@ -56,11 +59,15 @@ type File struct {
 	bufferClosed chan struct{}
 	//缓冲区冲刷时间间隔
 	flush time.Duration
+	//日志处理器名称
+	name string
+	//内部错误处理器
+	errorHandler contract.AtomicErrorHandler
 }
 
 func NewFile(level contract.Level, formatter contract.Formatter, path string) *File {
 	tmp := new(File)
-	tmp.level = level
+	tmp.level = contract.NewAtomicLevel(level)
 	tmp.formatter = formatter
 	tmp.bubble = false
 	tmp.setPath(path)
@@ -70,6 +77,7 @@ func NewFile(level contract.Level, formatter contract.Formatter, path string) *F
 	tmp.writeLock = new(sync.Mutex)
 	tmp.buffer = nil
 	tmp.closed = make(chan struct{})
+	tmp.name = "file"
 	return tmp
 }
 
@@ -88,7 +96,7 @@ func (r *File) goF() {
 	defer ticker.Stop()
 	defer func() {
 		if a := recover(); a != nil {
-			libLog.Println(fmt.Sprintf("File handler uncaught panic: %s", debug.Stack()))
+			r.handleError(contract.ErrorKindPanic, nil, fmt.Errorf("file handler uncaught panic: %v\n%s", a, debug.Stack()))
 			r.goF()
 		} else {
 			close(r.bufferClosed)
@@ -100,7 +108,7 @@ func (r *File) goF() {
 			r.writeLock.Lock()
 			if err := r.buffer.Flush(); err != nil {
 				r.writeLock.Unlock()
-				libLog.Println(err)
+				r.handleError(contract.ErrorKindFlush, nil, err)
 			} else {
 				r.writeLock.Unlock()
 			}
@@ -109,7 +117,7 @@ func (r *File) goF() {
 			r.writeLock.Lock()
 			if err := r.buffer.Flush(); err != nil {
 				r.writeLock.Unlock()
-				libLog.Println(err)
+				r.handleError(contract.ErrorKindFlush, nil, err)
 			} else {
 				r.writeLock.Unlock()
 			}
@@ -145,10 +153,48 @@ func (r *File) SetPrefix(prefix string) {
 	r.prefix = prefix
 }
 
+// SetLevel 修改日志等级，可以在运行时安全调用
+func (r *File) SetLevel(level contract.Level) {
+	r.level.SetLevel(level)
+}
+
+func (r *File) GetLevel() contract.Level {
+	return r.level.GetLevel()
+}
+
+// SetAtomicLevel 设置与其它日志处理器共享的日志等级，需要在写入日志之前调用
+func (r *File) SetAtomicLevel(level *contract.AtomicLevel) {
+	if level != nil {
+		r.level = level
+	}
+}
+
 func (r *File) SetBubble(bubble bool) {
 	r.bubble = bubble
 }
 
+func (r *File) SetName(name string) {
+	r.name = name
+}
+
+func (r *File) GetName() string {
+	return r.name
+}
+
+// SetErrorHandler 设置内部错误处理器，可以在运行时安全调用
+func (r *File) SetErrorHandler(handler contract.ErrorHandler) {
+	r.errorHandler.SetErrorHandler(handler)
+}
+
+func (r *File) GetErrorHandler() contract.ErrorHandler {
+	return r.errorHandler.GetErrorHandler()
+}
+
+// 处理内部错误
+func (r *File) handleError(kind contract.ErrorKind, record *contract.Record, err error) {
+	report.Error(r.errorHandler.GetErrorHandler(), &contract.Error{Handler: r.name, Kind: kind, Record: record, Err: err})
+}
+
 func (r *File) SetPerm(perm os.FileMode) {
 	r.perm = perm
 }
@@ -189,8 +235,24 @@ func (r *File) Close() error {
 	return nil
 }
 
+// Flush 将缓冲区中的日志写入日志文件
+func (r *File) Flush(ctx context.Context) error {
+	r.writeLock.Lock()
+	defer r.writeLock.Unlock()
+	select {
+	case <-r.closed:
+		return nil
+	default:
+		break
+	}
+	if r.buffer == nil || r.file == nil {
+		return nil
+	}
+	return r.buffer.Flush()
+}
+
 func (r *File) IsHandling(level contract.Level) bool {
-	return level <= r.level
+	return r.level.IsHandling(level)
 }
 
 // 找到日期下最后一个日志文件的索引值
@@ -304,12 +366,30 @@ func (r *File) openFile(logName string, t time.Time) error {
 }
 
 func (r *File) Handle(record *contract.Record) bool {
+	bubble, err := r.TryHandle(record)
+	if err != nil {
+		//释放写锁后再处理错误，错误经标准库 log 回流到本日志处理器时不会死锁
+		report.Error(r.errorHandler.GetErrorHandler(), err)
+	}
+	return bubble
+}
+
+// TryHandle 写入日志，写入失败时返回错误而不交给错误处理器
+func (r *File) TryHandle(record *contract.Record) (bool, *contract.Error) {
+	bubble, kind, err := r.handle(record)
+	if err != nil {
+		return bubble, &contract.Error{Handler: r.name, Kind: kind, Record: record, Err: err}
+	}
+	return bubble, nil
+}
+
+func (r *File) handle(record *contract.Record) (bool, contract.ErrorKind, error) {
 	r.writeLock.Lock()
 	defer r.writeLock.Unlock()
 	select {
 	case <-r.closed:
 		//强制返回false，让下一个日志handler继续处理日志信息
-		return false
+		return false, 0, nil
 	default:
 		break
 	}
@@ -328,19 +408,17 @@ func (r *File) Handle(record *contract.Record) bool {
 			}
 		}
 		if err != nil {
-			libLog.Println(err)
 			//强制返回false，让下一个日志handler继续处理日志信息
-			return false
+			return false, contract.ErrorKindOpen, err
 		}
 	}
 	//写入日志
 	if n, err := r.formatter.ToWriter(r.w, record); err == nil {
 		r.currentSize += n
-		return r.bubble
+		metrics.FileBytes.Add(r.name, 0, uint64(n))
+		return r.bubble, 0, nil
 	} else {
-		//错误，调用标准库日志打印错误
-		libLog.Println(err)
 		//强制返回false，让下一个日志handler继续处理日志信息
-		return false
+		return false, contract.ErrorKindWrite, err
 	}
 }
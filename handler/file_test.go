@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"fmt"
 	"github.com/buexplain/go-flog/contract"
 	"github.com/buexplain/go-flog/formatter"
@@ -526,3 +527,42 @@ func TestFileAwaitConcurrent(t *testing.T) {
 		t.Error("日志处理器的文件未关闭：", err)
 	}
 }
+
+// 测试冲刷缓冲区但不关闭日志处理器
+func TestFileFlush(t *testing.T) {
+	path, err := os.MkdirTemp("./", "test")
+	if err != nil {
+		t.Error("构建临时目录失败")
+	}
+	file := NewFile(contract.LevelDebug, formatter.NewLine(), path)
+	//设置一个2MiB的缓冲器，30秒刷新一次，保证日志都会写入到缓冲器中
+	file.SetBuffer(1024*1024*2, time.Second*30)
+	record := contract.NewRecord()
+	record.Level = contract.GetNameByLevel(contract.LevelInfo)
+	record.Message = "message"
+	file.Handle(record)
+	m, err := filepath.Glob(filepath.Join(path, "*.log"))
+	if err != nil || len(m) != 1 {
+		t.Error("获取日志处理的结果失败：", err)
+		return
+	}
+	if fi, err := os.Stat(m[0]); err != nil || fi.Size() != 0 {
+		t.Error("冲刷之前日志应该在缓冲区中", err)
+	}
+	if err := file.Flush(context.Background()); err != nil {
+		t.Error("冲刷日志处理器失败", err)
+	}
+	if fi, err := os.Stat(m[0]); err != nil || fi.Size() == 0 {
+		t.Error("冲刷之后日志应该写入日志文件", err)
+	}
+	//冲刷之后可以继续写入
+	if file.Handle(record) {
+		t.Error("冲刷之后写入日志失败")
+	}
+	if err := file.Close(); err != nil {
+		t.Error("日志处理测试失败：", err)
+	}
+	if err := os.RemoveAll(path); err != nil {
+		t.Error("日志处理器的文件未关闭：", err)
+	}
+}
@@ -2,19 +2,24 @@ package handler
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"github.com/buexplain/go-flog/contract"
 	formatter2 "github.com/buexplain/go-flog/formatter"
-	"io"
-	libLog "log"
+	"github.com/buexplain/go-flog/internal/metrics"
+	"github.com/buexplain/go-flog/internal/report"
 	"net/http"
 	"net/url"
+	"sync/atomic"
 	"time"
 )
 
 // HTTP http接口日志处理器
 type HTTP struct {
+	//正在发送的请求数量，放在结构体开头，保证32位平台上原子操作的内存对齐
+	inflight int64
 	//日志等级
-	level contract.Level
+	level *contract.AtomicLevel
 	//日志格式化处理器
 	formatter contract.Formatter
 	//是否阻止进入下一个日志处理器
@@ -25,11 +30,15 @@ type HTTP struct {
 	header http.Header
 	//超时设置
 	timeout time.Duration
+	//日志处理器名称
+	name string
+	//内部错误处理器
+	errorHandler contract.AtomicErrorHandler
 }
 
 func NewHTTP(level contract.Level, formatter contract.Formatter, url string) *HTTP {
 	tmp := new(HTTP)
-	tmp.level = level
+	tmp.level = contract.NewAtomicLevel(level)
 	tmp.formatter = formatter
 	tmp.bubble = false
 	tmp.url = url
@@ -40,14 +49,55 @@ func NewHTTP(level contract.Level, formatter contract.Formatter, url string) *HT
 		tmp.header.Set("Content-Type", "text/plain; charset=utf-8")
 	}
 	tmp.timeout = 5 * time.Second
+	tmp.name = "http"
 	return tmp
 }
 
+// SetLevel 修改日志等级，可以在运行时安全调用
+func (r *HTTP) SetLevel(level contract.Level) {
+	r.level.SetLevel(level)
+}
+
+func (r *HTTP) GetLevel() contract.Level {
+	return r.level.GetLevel()
+}
+
+// SetAtomicLevel 设置与其它日志处理器共享的日志等级，需要在写入日志之前调用
+func (r *HTTP) SetAtomicLevel(level *contract.AtomicLevel) *HTTP {
+	if level != nil {
+		r.level = level
+	}
+	return r
+}
+
 func (r *HTTP) SetBubble(bubble bool) *HTTP {
 	r.bubble = bubble
 	return r
 }
 
+func (r *HTTP) SetName(name string) *HTTP {
+	r.name = name
+	return r
+}
+
+func (r *HTTP) GetName() string {
+	return r.name
+}
+
+// SetErrorHandler 设置内部错误处理器，可以在运行时安全调用
+func (r *HTTP) SetErrorHandler(handler contract.ErrorHandler) {
+	r.errorHandler.SetErrorHandler(handler)
+}
+
+func (r *HTTP) GetErrorHandler() contract.ErrorHandler {
+	return r.errorHandler.GetErrorHandler()
+}
+
+// 构建内部错误
+func (r *HTTP) newError(kind contract.ErrorKind, record *contract.Record, err error) *contract.Error {
+	return &contract.Error{Handler: r.name, Kind: kind, Record: record, Err: err}
+}
+
 func (r *HTTP) SetHeader(h http.Header) *HTTP {
 	r.header = h
 	return r
@@ -62,17 +112,42 @@ func (r *HTTP) Close() error {
 	return nil
 }
 
+// Flush 等待正在发送的请求完成
+func (r *HTTP) Flush(ctx context.Context) error {
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	for atomic.LoadInt64(&r.inflight) > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			break
+		}
+	}
+	return nil
+}
+
 // IsHandling 判断当前处理器是否可以处理日志
 func (r *HTTP) IsHandling(level contract.Level) bool {
-	return level <= r.level
+	return r.level.IsHandling(level)
 }
 
 // Handle 处理器入口
 func (r *HTTP) Handle(record *contract.Record) bool {
+	bubble, err := r.TryHandle(record)
+	if err != nil {
+		report.Error(r.errorHandler.GetErrorHandler(), err)
+	}
+	return bubble
+}
+
+// TryHandle 发送日志，发送失败或者响应状态码表示失败时返回错误而不交给错误处理器
+func (r *HTTP) TryHandle(record *contract.Record) (bool, *contract.Error) {
+	atomic.AddInt64(&r.inflight, 1)
+	defer atomic.AddInt64(&r.inflight, -1)
 	request, err := http.NewRequest(http.MethodPost, r.url, nil)
 	if err != nil {
-		libLog.Println(err)
-		return false
+		return false, r.newError(contract.ErrorKindRequest, record, err)
 	}
 
 	//克隆头部信息
@@ -86,23 +161,28 @@ func (r *HTTP) Handle(record *contract.Record) bool {
 	var buf *bytes.Buffer
 	buf, err = r.formatter.ToBuffer(record)
 	if err != nil {
-		libLog.Println(err)
-		return false
+		return false, r.newError(contract.ErrorKindFormat, record, err)
 	}
-	request.Body = io.NopCloser(buf)
+	//请求体关闭时缓冲区归还对象池
+	request.Body = contract.NewBufferReader(buf)
 
 	client := http.Client{Timeout: r.timeout}
 	var resp *http.Response
+	start := time.Now()
 	resp, err = client.Do(request)
+	metrics.HTTPDuration.Observe(r.name, time.Since(start).Seconds())
 
-	if err == nil {
-		_ = resp.Body.Close()
-	} else {
-		if e, ok := err.(*url.Error); !ok || !e.Timeout() {
-			libLog.Println(err)
+	if err != nil {
+		if e, ok := err.(*url.Error); ok && e.Timeout() {
+			return false, r.newError(contract.ErrorKindTimeout, record, err)
 		}
-		return false
+		return false, r.newError(contract.ErrorKindRequest, record, err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		//接口拒绝了日志，强制返回false，让下一个日志handler继续处理日志信息
+		return false, r.newError(contract.ErrorKindRequest, record, fmt.Errorf("unexpected status: %s", resp.Status))
 	}
 
-	return r.bubble
+	return r.bubble, nil
 }
@@ -7,6 +7,9 @@ import (
 	"github.com/buexplain/go-flog/handler"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -70,3 +73,63 @@ func TestHTTP(t *testing.T) {
 	//停止http服务器
 	_ = server.Shutdown(context.Background())
 }
+
+func TestHTTPErrorHandler(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = io.ReadAll(request.Body)
+		if request.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		writer.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	var kinds []contract.ErrorKind
+	errorHandler := contract.ErrorHandlerFunc(func(err *contract.Error) {
+		if err.Handler != "remote" || err.Record == nil || err.Record.Message != "message" {
+			t.Errorf("内部错误的信息错误 %+v", err)
+		}
+		kinds = append(kinds, err.Kind)
+	})
+	record := contract.NewRecord()
+	record.Level = contract.GetNameByLevel(contract.LevelError)
+	record.Message = "message"
+	h := handler.NewHTTP(contract.LevelDebug, formatter.NewJSON(), server.URL).SetName("remote").SetBubble(true)
+	h.SetErrorHandler(errorHandler)
+	if h.Handle(record) {
+		t.Error("响应状态码表示失败时应该让下一个日志处理器继续处理")
+	}
+	if _, err := h.TryHandle(record); err == nil || err.Kind != contract.ErrorKindRequest {
+		t.Error("响应状态码表示失败时应该返回错误", err)
+	}
+	h = handler.NewHTTP(contract.LevelDebug, formatter.NewJSON(), server.URL+"/slow").SetName("remote").SetTimeout(50 * time.Millisecond)
+	h.SetErrorHandler(errorHandler)
+	h.Handle(record)
+	if len(kinds) != 2 || kinds[0] != contract.ErrorKindRequest || kinds[1] != contract.ErrorKindTimeout {
+		t.Error("http日志处理器没有报告请求错误与超时错误", kinds)
+	}
+}
+
+func TestHTTPTimeoutStderr(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = io.ReadAll(request.Body)
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+	//没有设置错误处理器时，超时错误写入标准错误
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = w
+	record := contract.NewRecord()
+	record.Level = contract.GetNameByLevel(contract.LevelError)
+	record.Message = "message"
+	handler.NewHTTP(contract.LevelDebug, formatter.NewJSON(), server.URL).SetName("remote").SetTimeout(50 * time.Millisecond).Handle(record)
+	os.Stderr = stderr
+	_ = w.Close()
+	output, _ := io.ReadAll(r)
+	if !strings.Contains(string(output), "flog: handler remote timeout error") {
+		t.Error("没有设置错误处理器时，超时错误应该写入标准错误", string(output))
+	}
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/internal/report"
+	"log/slog"
+	"sort"
+)
+
+// Slog 将日志转发给任意 slog.Handler 的日志处理器
+// 日志的结构化字段与附加信息转换为 slog 的属性，附加信息中的 map[string]interface{} 转换为 slog 的分组
+type Slog struct {
+	//日志等级
+	level *contract.AtomicLevel
+	//接收日志的 slog.Handler
+	handler slog.Handler
+	//是否阻止进入下一个日志处理器
+	bubble bool
+	//日志处理器名称
+	name string
+	//内部错误处理器
+	errorHandler contract.AtomicErrorHandler
+}
+
+func NewSlog(level contract.Level, handler slog.Handler) *Slog {
+	tmp := new(Slog)
+	tmp.level = contract.NewAtomicLevel(level)
+	tmp.handler = handler
+	tmp.bubble = false
+	tmp.name = "slog"
+	return tmp
+}
+
+// SetLevel 修改日志等级，可以在运行时安全调用
+func (r *Slog) SetLevel(level contract.Level) {
+	r.level.SetLevel(level)
+}
+
+func (r *Slog) GetLevel() contract.Level {
+	return r.level.GetLevel()
+}
+
+// SetAtomicLevel 设置与其它日志处理器共享的日志等级，需要在写入日志之前调用
+func (r *Slog) SetAtomicLevel(level *contract.AtomicLevel) *Slog {
+	if level != nil {
+		r.level = level
+	}
+	return r
+}
+
+func (r *Slog) SetBubble(bubble bool) *Slog {
+	r.bubble = bubble
+	return r
+}
+
+func (r *Slog) SetName(name string) *Slog {
+	r.name = name
+	return r
+}
+
+func (r *Slog) GetName() string {
+	return r.name
+}
+
+// SetErrorHandler 设置内部错误处理器，可以在运行时安全调用
+func (r *Slog) SetErrorHandler(handler contract.ErrorHandler) {
+	r.errorHandler.SetErrorHandler(handler)
+}
+
+func (r *Slog) GetErrorHandler() contract.ErrorHandler {
+	return r.errorHandler.GetErrorHandler()
+}
+
+func (r *Slog) Close() error {
+	return nil
+}
+
+// IsHandling 判断当前处理器是否可以处理日志，同时要求 slog.Handler 开启了对应的等级
+func (r *Slog) IsHandling(level contract.Level) bool {
+	return r.level.IsHandling(level) && r.handler.Enabled(context.Background(), contract.ToSlogLevel(level))
+}
+
+// Handle 处理器入口
+func (r *Slog) Handle(record *contract.Record) bool {
+	rec := slog.NewRecord(record.Time, contract.ToSlogLevel(contract.GetLevelByName(record.Level)), record.Message, 0)
+	if record.Channel != "" {
+		rec.AddAttrs(slog.String("channel", record.Channel))
+	}
+	if record.Context != nil {
+		rec.AddAttrs(slog.Any("context", record.Context))
+	}
+	for _, field := range record.Fields {
+		rec.AddAttrs(fieldToSlogAttr(field))
+	}
+	//附加信息按键名排序，保证输出稳定
+	if len(record.Extra) > 0 {
+		keys := make([]string, 0, len(record.Extra))
+		for k := range record.Extra {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			rec.AddAttrs(valueToSlogAttr(k, record.Extra[k]))
+		}
+	}
+	if err := r.handler.Handle(context.Background(), rec); err != nil {
+		report.Error(r.errorHandler.GetErrorHandler(), &contract.Error{Handler: r.name, Kind: contract.ErrorKindWrite, Record: record, Err: err})
+		//强制返回false
+		//让下一个日志handler继续处理日志信息
+		return false
+	}
+	return r.bubble
+}
+
+// 将结构化字段转换为 slog 的属性
+func fieldToSlogAttr(field contract.Field) slog.Attr {
+	switch field.Type {
+	case contract.FieldTypeString:
+		return slog.String(field.Key, field.String)
+	case contract.FieldTypeInt64:
+		return slog.Int64(field.Key, field.Integer)
+	case contract.FieldTypeUint64:
+		return slog.Uint64(field.Key, uint64(field.Integer))
+	default:
+		return valueToSlogAttr(field.Key, field.Value())
+	}
+}
+
+// 将任意值转换为 slog 的属性，map[string]interface{} 转换为分组
+func valueToSlogAttr(key string, value interface{}) slog.Attr {
+	group, ok := value.(map[string]interface{})
+	if !ok {
+		return slog.Any(key, value)
+	}
+	keys := make([]string, 0, len(group))
+	for k := range group {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]slog.Attr, 0, len(keys))
+	for _, k := range keys {
+		attrs = append(attrs, valueToSlogAttr(k, group[k]))
+	}
+	return slog.Attr{Key: key, Value: slog.GroupValue(attrs...)}
+}
@@ -0,0 +1,39 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/buexplain/go-flog"
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/handler"
+	"log/slog"
+	"reflect"
+	"testing"
+)
+
+func TestSlog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	h := handler.NewSlog(contract.LevelDebug, slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	if h.IsHandling(contract.LevelDebug) {
+		t.Error("slog.Handler 没有开启debug等级，日志处理器不应该处理debug日志")
+	}
+	logger := flog.New("channel", h).With("request", map[string]interface{}{"method": "GET"})
+	logger.Critical("message", flog.Int("status", 200), flog.String("user", "刘备"))
+	var result map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatal("slog 输出的不是json", err, buf.String())
+	}
+	expect := map[string]interface{}{
+		"level":   "ERROR+4",
+		"msg":     "message",
+		"channel": "channel",
+		"status":  float64(200),
+		"user":    "刘备",
+		"request": map[string]interface{}{"method": "GET"},
+	}
+	for k, v := range expect {
+		if !reflect.DeepEqual(result[k], v) {
+			t.Errorf("转发给 slog 的属性 %s 期待为 %v，实际为 %v", k, v, result[k])
+		}
+	}
+}
@@ -1,47 +1,103 @@
 package handler
 
 import (
+	"context"
 	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/internal/report"
 	"os"
 )
 
 // STD 标准输出与标准出错日志处理器
 type STD struct {
 	//日志等级
-	level contract.Level
+	level *contract.AtomicLevel
 	//日志格式化处理器
 	formatter contract.Formatter
 	//是否阻止进入下一个日志处理器
 	bubble bool
 	//标准输出与标准错误分割的日志等级
 	dst contract.Level
+	//日志处理器名称
+	name string
+	//内部错误处理器
+	errorHandler contract.AtomicErrorHandler
 }
 
 func NewSTD(level contract.Level, formatter contract.Formatter, dst contract.Level) *STD {
 	tmp := new(STD)
-	tmp.level = level
+	tmp.level = contract.NewAtomicLevel(level)
 	tmp.formatter = formatter
 	tmp.bubble = false
 	tmp.dst = dst
+	tmp.name = "std"
 	return tmp
 }
 
+// SetLevel 修改日志等级，可以在运行时安全调用
+func (r *STD) SetLevel(level contract.Level) {
+	r.level.SetLevel(level)
+}
+
+func (r *STD) GetLevel() contract.Level {
+	return r.level.GetLevel()
+}
+
+// SetAtomicLevel 设置与其它日志处理器共享的日志等级，需要在写入日志之前调用
+func (r *STD) SetAtomicLevel(level *contract.AtomicLevel) *STD {
+	if level != nil {
+		r.level = level
+	}
+	return r
+}
+
 func (r *STD) SetBubble(bubble bool) *STD {
 	r.bubble = bubble
 	return r
 }
 
+func (r *STD) SetName(name string) *STD {
+	r.name = name
+	return r
+}
+
+func (r *STD) GetName() string {
+	return r.name
+}
+
+// SetErrorHandler 设置内部错误处理器，可以在运行时安全调用
+func (r *STD) SetErrorHandler(handler contract.ErrorHandler) {
+	r.errorHandler.SetErrorHandler(handler)
+}
+
+func (r *STD) GetErrorHandler() contract.ErrorHandler {
+	return r.errorHandler.GetErrorHandler()
+}
+
 func (r *STD) Close() error {
 	return nil
 }
 
+// Flush 标准输出与标准出错没有缓冲，无需冲刷
+func (r *STD) Flush(ctx context.Context) error {
+	return nil
+}
+
 // IsHandling 判断当前处理器是否可以处理日志
 func (r *STD) IsHandling(level contract.Level) bool {
-	return level <= r.level
+	return r.level.IsHandling(level)
 }
 
 // Handle 处理器入口
 func (r *STD) Handle(record *contract.Record) bool {
+	bubble, err := r.TryHandle(record)
+	if err != nil {
+		report.Error(r.errorHandler.GetErrorHandler(), err)
+	}
+	return bubble
+}
+
+// TryHandle 写入日志，写入失败时返回错误而不交给错误处理器
+func (r *STD) TryHandle(record *contract.Record) (bool, *contract.Error) {
 	var err error
 	if r.dst == -1 {
 		_, err = r.formatter.ToWriter(os.Stdout, record)
@@ -55,7 +111,7 @@ func (r *STD) Handle(record *contract.Record) bool {
 	if err != nil {
 		//强制返回false
 		//让下一个日志handler继续处理日志信息
-		return false
+		return false, &contract.Error{Handler: r.name, Kind: contract.ErrorKindWrite, Record: record, Err: err}
 	}
-	return r.bubble
+	return r.bubble, nil
 }
@@ -0,0 +1,390 @@
+// Package metrics 日志库自身的运行指标，由日志收集器、日志处理器与格式化处理器在运行时累加
+// 指标只依赖标准库，由 metrics 包以 expvar 与 Prometheus 文本格式对外暴露
+package metrics
+
+import (
+	"github.com/buexplain/go-flog/contract"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Counter 计数器，按一个字符串标签分组，可以再按一个取值固定的枚举标签细分
+// 按字符串标签查找计数时不分配内存，可以用于收集日志的热路径
+type Counter struct {
+	//指标名称
+	name string
+	//指标说明
+	help string
+	//字符串标签名称
+	label string
+	//枚举标签名称，空字符串表示没有枚举标签
+	enum string
+	//枚举标签的取值，下标即枚举值
+	enumValues []string
+	lock       *sync.RWMutex
+	//字符串标签取值对应的计数，每个枚举值一个计数
+	values map[string][]uint64
+}
+
+func newCounter(name, help, label, enum string, enumValues []string) *Counter {
+	tmp := new(Counter)
+	tmp.name = name
+	tmp.help = help
+	tmp.label = label
+	tmp.enum = enum
+	tmp.enumValues = enumValues
+	if tmp.enumValues == nil {
+		tmp.enumValues = []string{""}
+	}
+	tmp.lock = new(sync.RWMutex)
+	tmp.values = map[string][]uint64{}
+	return tmp
+}
+
+// 返回字符串标签取值对应的计数，不存在则创建
+func (r *Counter) get(label string) []uint64 {
+	r.lock.RLock()
+	values, ok := r.values[label]
+	r.lock.RUnlock()
+	if ok {
+		return values
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if values, ok = r.values[label]; !ok {
+		values = make([]uint64, len(r.enumValues))
+		r.values[label] = values
+	}
+	return values
+}
+
+// Add 累加计数，enum 是枚举标签的取值下标，没有枚举标签时传0，超出范围的取值被忽略
+func (r *Counter) Add(label string, enum int, n uint64) {
+	if enum < 0 || enum >= len(r.enumValues) {
+		return
+	}
+	atomic.AddUint64(&r.get(label)[enum], n)
+}
+
+// Value 返回计数
+func (r *Counter) Value(label string, enum int) uint64 {
+	if enum < 0 || enum >= len(r.enumValues) {
+		return 0
+	}
+	r.lock.RLock()
+	values, ok := r.values[label]
+	r.lock.RUnlock()
+	if !ok {
+		return 0
+	}
+	return atomic.LoadUint64(&values[enum])
+}
+
+// 按字符串标签排序后的计数快照
+func (r *Counter) snapshot() (labels []string, values [][]uint64) {
+	r.lock.RLock()
+	labels = make([]string, 0, len(r.values))
+	for k := range r.values {
+		labels = append(labels, k)
+	}
+	r.lock.RUnlock()
+	sort.Strings(labels)
+	values = make([][]uint64, len(labels))
+	for i, label := range labels {
+		src := r.get(label)
+		values[i] = make([]uint64, len(src))
+		for j := range src {
+			values[i][j] = atomic.LoadUint64(&src[j])
+		}
+	}
+	return labels, values
+}
+
+func (r *Counter) getName() string {
+	return r.name
+}
+
+func (r *Counter) writeText(w *textWriter) {
+	w.header(r.name, r.help, "counter")
+	labels, values := r.snapshot()
+	for i, label := range labels {
+		for j, v := range values[i] {
+			if r.enum == "" {
+				w.sample(r.name, v, r.label, label)
+			} else if v > 0 {
+				w.sample(r.name, v, r.label, label, r.enum, r.enumValues[j])
+			}
+		}
+	}
+}
+
+func (r *Counter) expvar() interface{} {
+	labels, values := r.snapshot()
+	tmp := make(map[string]interface{}, len(labels))
+	for i, label := range labels {
+		if r.enum == "" {
+			tmp[label] = values[i][0]
+			continue
+		}
+		enum := make(map[string]uint64, len(values[i]))
+		for j, v := range values[i] {
+			if v > 0 {
+				enum[r.enumValues[j]] = v
+			}
+		}
+		tmp[label] = enum
+	}
+	return tmp
+}
+
+// 单个标签取值的直方图数据
+type histogramValue struct {
+	//观测值总和，float64 的二进制表示
+	sum uint64
+	//观测次数
+	count uint64
+	//各个区间的观测次数，最后一个是超出所有区间上限的观测次数
+	buckets []uint64
+}
+
+// Histogram 直方图，按一个字符串标签分组
+type Histogram struct {
+	name  string
+	help  string
+	label string
+	//区间上限，从小到大排列
+	bounds []float64
+	lock   *sync.RWMutex
+	values map[string]*histogramValue
+}
+
+func newHistogram(name, help, label string, bounds []float64) *Histogram {
+	tmp := new(Histogram)
+	tmp.name = name
+	tmp.help = help
+	tmp.label = label
+	tmp.bounds = bounds
+	tmp.lock = new(sync.RWMutex)
+	tmp.values = map[string]*histogramValue{}
+	return tmp
+}
+
+func (r *Histogram) get(label string) *histogramValue {
+	r.lock.RLock()
+	value, ok := r.values[label]
+	r.lock.RUnlock()
+	if ok {
+		return value
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if value, ok = r.values[label]; !ok {
+		value = &histogramValue{buckets: make([]uint64, len(r.bounds)+1)}
+		r.values[label] = value
+	}
+	return value
+}
+
+// Observe 记录一次观测值
+func (r *Histogram) Observe(label string, v float64) {
+	value := r.get(label)
+	atomic.AddUint64(&value.buckets[sort.SearchFloat64s(r.bounds, v)], 1)
+	for {
+		old := atomic.LoadUint64(&value.sum)
+		if atomic.CompareAndSwapUint64(&value.sum, old, math.Float64bits(math.Float64frombits(old)+v)) {
+			break
+		}
+	}
+	atomic.AddUint64(&value.count, 1)
+}
+
+// Count 返回观测次数
+func (r *Histogram) Count(label string) uint64 {
+	r.lock.RLock()
+	value, ok := r.values[label]
+	r.lock.RUnlock()
+	if !ok {
+		return 0
+	}
+	return atomic.LoadUint64(&value.count)
+}
+
+// 按字符串标签排序后的直方图快照，区间计数已经累加为不超过该区间上限的观测次数
+func (r *Histogram) snapshot() (labels []string, values []histogramValue) {
+	r.lock.RLock()
+	labels = make([]string, 0, len(r.values))
+	for k := range r.values {
+		labels = append(labels, k)
+	}
+	r.lock.RUnlock()
+	sort.Strings(labels)
+	values = make([]histogramValue, len(labels))
+	for i, label := range labels {
+		src := r.get(label)
+		value := histogramValue{buckets: make([]uint64, len(src.buckets))}
+		var cumulative uint64
+		for j := range src.buckets {
+			cumulative += atomic.LoadUint64(&src.buckets[j])
+			value.buckets[j] = cumulative
+		}
+		value.sum = atomic.LoadUint64(&src.sum)
+		//并发观测时区间计数与观测次数可能不一致，以区间计数为准
+		value.count = cumulative
+		values[i] = value
+	}
+	return labels, values
+}
+
+func (r *Histogram) getName() string {
+	return r.name
+}
+
+func (r *Histogram) writeText(w *textWriter) {
+	w.header(r.name, r.help, "histogram")
+	labels, values := r.snapshot()
+	for i, label := range labels {
+		for j, bound := range r.bounds {
+			w.sample(r.name+"_bucket", values[i].buckets[j], r.label, label, "le", formatFloat(bound))
+		}
+		w.sample(r.name+"_bucket", values[i].count, r.label, label, "le", "+Inf")
+		w.sampleFloat(r.name+"_sum", math.Float64frombits(values[i].sum), r.label, label)
+		w.sample(r.name+"_count", values[i].count, r.label, label)
+	}
+}
+
+func (r *Histogram) expvar() interface{} {
+	labels, values := r.snapshot()
+	tmp := make(map[string]interface{}, len(labels))
+	for i, label := range labels {
+		buckets := make(map[string]uint64, len(r.bounds)+1)
+		for j, bound := range r.bounds {
+			buckets[formatFloat(bound)] = values[i].buckets[j]
+		}
+		buckets["+Inf"] = values[i].count
+		tmp[label] = map[string]interface{}{
+			"count":   values[i].count,
+			"sum":     math.Float64frombits(values[i].sum),
+			"buckets": buckets,
+		}
+	}
+	return tmp
+}
+
+// 指标
+type metric interface {
+	getName() string
+	writeText(w *textWriter)
+	expvar() interface{}
+}
+
+// 日志等级名称，下标即日志等级
+func levelNames() []string {
+	tmp := make([]string, contract.LevelDebug+1)
+	for level := contract.LevelEmergency; level <= contract.LevelDebug; level++ {
+		tmp[level] = contract.GetNameByLevel(level)
+	}
+	return tmp
+}
+
+// 内部错误类型名称，下标即错误类型
+func errorKindNames() []string {
+	tmp := make([]string, 0, 8)
+	for kind := contract.ErrorKind(0); kind < math.MaxUint8 && kind.String() != "unknown"; kind++ {
+		tmp = append(tmp, kind.String())
+	}
+	return tmp
+}
+
+var (
+	// Records 进入日志处理器的日志数量
+	Records = newCounter("flog_records_total", "Records emitted to handlers.", "channel", "level", levelNames())
+	// Dropped 异步日志队列满载时被丢弃的日志数量
+	Dropped = newCounter("flog_async_dropped_total", "Records dropped by async queue overflow.", "channel", "level", levelNames())
+	// HandlerErrors 日志处理器的内部错误数量
+	HandlerErrors = newCounter("flog_handler_errors_total", "Internal errors reported by handlers.", "handler", "kind", errorKindNames())
+	// FormatErrors 格式化日志失败的数量
+	FormatErrors = newCounter("flog_format_errors_total", "Records the formatter failed to encode.", "formatter", "", nil)
+	// FileBytes 文件日志处理器写入的字节数
+	FileBytes = newCounter("flog_file_written_bytes_total", "Bytes written by file handlers.", "handler", "", nil)
+	// HTTPDuration http接口日志处理器的请求耗时，单位秒
+	HTTPDuration = newHistogram("flog_http_request_duration_seconds", "Latency of http handler requests.", "handler", []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10})
+	// DingTalkDeduplicated 钉钉日志处理器压缩掉的重复日志数量
+	DingTalkDeduplicated = newCounter("flog_dingtalk_deduplicated_total", "Records deduplicated by dingtalk compress.", "handler", "", nil)
+)
+
+// 全部指标，按输出顺序排列
+var all = []metric{Records, Dropped, HandlerErrors, FormatErrors, FileBytes, HTTPDuration, DingTalkDeduplicated}
+
+// WriteText 以 Prometheus 文本格式输出全部指标
+func WriteText(w io.Writer) error {
+	tmp := &textWriter{w: w}
+	for _, v := range all {
+		v.writeText(tmp)
+	}
+	return tmp.err
+}
+
+// Snapshot 返回全部指标的快照，以指标名称为键，用于 expvar
+func Snapshot() map[string]interface{} {
+	tmp := make(map[string]interface{}, len(all))
+	for _, v := range all {
+		tmp[v.getName()] = v.expvar()
+	}
+	return tmp
+}
+
+// Prometheus 文本格式的输出器，记录第一个写入错误
+type textWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (r *textWriter) write(s string) {
+	if r.err == nil {
+		_, r.err = io.WriteString(r.w, s)
+	}
+}
+
+func (r *textWriter) header(name, help, typ string) {
+	r.write("# HELP " + name + " " + help + "\n# TYPE " + name + " " + typ + "\n")
+}
+
+func (r *textWriter) sample(name string, v uint64, labels ...string) {
+	r.write(name + formatLabels(labels) + " " + strconv.FormatUint(v, 10) + "\n")
+}
+
+func (r *textWriter) sampleFloat(name string, v float64, labels ...string) {
+	r.write(name + formatLabels(labels) + " " + formatFloat(v) + "\n")
+}
+
+// 标签值需要转义的字符
+var labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// 格式化标签，labels 按名称、取值交替排列
+func formatLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	b := strings.Builder{}
+	b.WriteByte('{')
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(labels[i])
+		b.WriteString(`="`)
+		b.WriteString(labelReplacer.Replace(labels[i+1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
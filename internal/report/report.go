@@ -0,0 +1,116 @@
+// Package report 输出日志库内部的错误
+// 内部错误交给标准库 log 输出，标准库 log 被重定向到日志收集器时，内部错误会回流到日志处理器
+// 为了避免递归，以下情况的内部错误直接写入标准错误，判断依据是出错的日志本身，不影响其它go程的日志：
+// 1. 处理通过重定向写入的日志时产生的内部错误
+// 2. 处理回流的内部错误时产生的内部错误
+package report
+
+import (
+	"fmt"
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/internal/metrics"
+	"io"
+	libLog "log"
+	"os"
+	"sync"
+)
+
+// Key 回流的内部错误在日志附加信息中的标记
+const Key = "FlogInternal"
+
+// Redirected 判断标准库 log 的输出是否被重定向到日志收集器，由 flog 包注册
+var Redirected = func(w io.Writer) bool {
+	return false
+}
+
+// Reflux 将内部错误写入标准库 log 重定向的日志收集器，并标记为回流的内部错误，没有被重定向时返回 false，由 flog 包注册
+var Reflux = func(w io.Writer, message string) bool {
+	return false
+}
+
+var (
+	//重定向时的内部错误队列
+	queue chan string
+	once  sync.Once
+)
+
+// Record 输出处理日志时产生的内部错误，处理的是回流的内部错误或者通过重定向写入的日志时，直接写入标准错误
+func Record(record *contract.Record, v ...interface{}) {
+	internal := false
+	if record != nil {
+		_, internal = record.Extra[Key]
+		internal = internal || record.RefluxDisabled()
+	}
+	output(fmt.Sprintln(v...), internal)
+}
+
+func output(message string, internal bool) {
+	if internal {
+		_, _ = io.WriteString(os.Stderr, message)
+		return
+	}
+	if !Redirected(libLog.Writer()) {
+		write(message)
+		return
+	}
+	//标准库 log 写入日志时持有锁，日志收集器又可能在等待日志队列空闲
+	//所以交给独立的go程写入，避免写入go程与标准库 log 互相等待
+	once.Do(func() {
+		queue = make(chan string, 1024)
+		go func() {
+			for message := range queue {
+				//写入前重定向可能已经恢复，此时按普通的标准库 log 输出
+				if !Reflux(libLog.Writer(), message) {
+					write(message)
+				}
+			}
+		}()
+	})
+	select {
+	case queue <- message:
+		break
+	default:
+		_, _ = io.WriteString(os.Stderr, message)
+	}
+}
+
+func write(message string) {
+	_ = libLog.Output(4, message)
+}
+
+// Error 交给错误处理器处理内部错误，没有设置错误处理器时输出到标准库 log
+// 没有设置错误处理器时，请求超时的错误直接写入标准错误，不经过标准库 log，避免网络抖动时回流到日志处理器刷屏
+func Error(handler contract.ErrorHandler, err *contract.Error) {
+	metrics.HandlerErrors.Add(err.Handler, int(err.Kind), 1)
+	if handler != nil {
+		defer func() {
+			//错误处理器恐慌时，退回到默认的输出方式
+			if a := recover(); a != nil {
+				Record(err.Record, err.Error(), a)
+			}
+		}()
+		handler.HandleError(err)
+		return
+	}
+	if err.Kind == contract.ErrorKindTimeout {
+		output(fmt.Sprintln(err.Error()), true)
+		return
+	}
+	Record(err.Record, err.Error())
+}
+
+// Panic 将恐慌值转换为错误
+func Panic(v interface{}) error {
+	if err, ok := v.(error); ok {
+		return err
+	}
+	return fmt.Errorf("%v", v)
+}
+
+// Name 返回日志处理器的名称，没有名称的日志处理器返回类型名称
+func Name(handler contract.Handler) string {
+	if namer, ok := handler.(interface{ GetName() string }); ok {
+		return namer.GetName()
+	}
+	return fmt.Sprintf("%T", handler)
+}
@@ -0,0 +1,252 @@
+package flog
+
+import (
+	"encoding/json"
+	"github.com/buexplain/go-flog/contract"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AtomicLevel 可以在运行时安全修改的日志等级，可以在多个日志处理器之间共享
+type AtomicLevel = contract.AtomicLevel
+
+func NewAtomicLevel(level contract.Level) *AtomicLevel {
+	return contract.NewAtomicLevel(level)
+}
+
+// SetLevel 修改所有实现了 contract.Leveler 的日志处理器的日志等级
+func (r *Logger) SetLevel(level contract.Level) {
+	for _, v := range r.GetHandlers() {
+		if leveler, ok := v.(contract.Leveler); ok {
+			leveler.SetLevel(level)
+		}
+	}
+}
+
+// 保存各个日志处理器的日志等级，返回的函数将各个日志处理器分别恢复为保存时的日志等级
+func (r *Logger) saveLevels() func() {
+	type saved struct {
+		leveler contract.Leveler
+		level   contract.Level
+	}
+	var levels []saved
+	for _, v := range r.GetHandlers() {
+		if leveler, ok := v.(contract.Leveler); ok {
+			levels = append(levels, saved{leveler: leveler, level: leveler.GetLevel()})
+		}
+	}
+	return func() {
+		for _, v := range levels {
+			v.leveler.SetLevel(v.level)
+		}
+	}
+}
+
+// 可以保存并恢复日志等级的接口，SetLevel 会修改多个日志等级时实现
+type levelSaver interface {
+	saveLevels() func()
+}
+
+// 保存日志等级，返回恢复为保存时的日志等级的函数
+func saveLevel(leveler contract.Leveler) func() {
+	if saver, ok := leveler.(levelSaver); ok {
+		return saver.saveLevels()
+	}
+	level := leveler.GetLevel()
+	return func() {
+		leveler.SetLevel(level)
+	}
+}
+
+// GetLevel 返回日志处理器可以处理的最详细的日志等级，没有日志处理器可以处理任何日志时返回 LevelEmergency
+func (r *Logger) GetLevel() contract.Level {
+	handlers := r.GetHandlers()
+	for level := contract.LevelDebug; level > contract.LevelEmergency; level-- {
+		for _, v := range handlers {
+			if v.IsHandling(level) {
+				return level
+			}
+		}
+	}
+	return contract.LevelEmergency
+}
+
+// LevelHandler 通过http查看与调整日志收集器或者日志处理器的日志等级
+// GET  ?name=xxx 查看指定名称的日志等级，不传名称则查看所有
+// PUT  ?name=xxx&level=debug&duration=10m 调整日志等级，duration 大于0时，到期后自动恢复为调整前的日志等级
+// PUT 请求也可以通过json请求体传参：{"level":"debug","duration":"10m"}
+type LevelHandler struct {
+	lock *sync.Mutex
+	//已注册的日志等级
+	levels map[string]contract.Leveler
+	//临时调整日志等级后的恢复定时器
+	timers map[string]*time.Timer
+	//恢复临时调整日志等级前的日志等级的函数，日志收集器的各个日志处理器分别恢复
+	previous map[string]func()
+}
+
+func NewLevelHandler() *LevelHandler {
+	tmp := new(LevelHandler)
+	tmp.lock = new(sync.Mutex)
+	tmp.levels = map[string]contract.Leveler{}
+	tmp.timers = map[string]*time.Timer{}
+	tmp.previous = map[string]func(){}
+	return tmp
+}
+
+// Register 注册日志等级，*Logger、*AtomicLevel 以及各个日志处理器都可以注册
+func (r *LevelHandler) Register(name string, leveler contract.Leveler) *LevelHandler {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.levels[name] = leveler
+	return r
+}
+
+// Unregister 注销日志等级，并取消未到期的恢复定时器
+func (r *LevelHandler) Unregister(name string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if timer, ok := r.timers[name]; ok {
+		timer.Stop()
+		delete(r.timers, name)
+		delete(r.previous, name)
+	}
+	delete(r.levels, name)
+}
+
+// 日志等级的http响应
+type levelState struct {
+	Name   string `json:"name"`
+	Level  string `json:"level"`
+	Revert string `json:"revert,omitempty"`
+}
+
+// 调整日志等级的http请求
+type levelRequest struct {
+	Level    string `json:"level"`
+	Duration string `json:"duration"`
+}
+
+func (r *LevelHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	name := req.URL.Query().Get("name")
+	switch req.Method {
+	case http.MethodGet:
+		if name == "" {
+			r.writeJSON(w, http.StatusOK, r.states())
+			return
+		}
+		state, ok := r.state(name)
+		if !ok {
+			r.writeError(w, http.StatusNotFound, "unknown name: "+name)
+			return
+		}
+		r.writeJSON(w, http.StatusOK, state)
+	case http.MethodPut, http.MethodPost:
+		param := levelRequest{Level: req.URL.Query().Get("level"), Duration: req.URL.Query().Get("duration")}
+		if strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+			if err := json.NewDecoder(io.LimitReader(req.Body, 4096)).Decode(&param); err != nil {
+				r.writeError(w, http.StatusBadRequest, "invalid body: "+err.Error())
+				return
+			}
+		}
+		level, ok := contract.ParseLevel(param.Level)
+		if !ok {
+			r.writeError(w, http.StatusBadRequest, "invalid level: "+param.Level)
+			return
+		}
+		var duration time.Duration
+		if param.Duration != "" {
+			var err error
+			if duration, err = time.ParseDuration(param.Duration); err != nil || duration < 0 {
+				r.writeError(w, http.StatusBadRequest, "invalid duration: "+param.Duration)
+				return
+			}
+		}
+		state, ok := r.setLevel(name, level, duration)
+		if !ok {
+			r.writeError(w, http.StatusNotFound, "unknown name: "+name)
+			return
+		}
+		r.writeJSON(w, http.StatusOK, state)
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		r.writeError(w, http.StatusMethodNotAllowed, "method not allowed: "+req.Method)
+	}
+}
+
+// 调整日志等级，duration 大于0时，到期后恢复为调整前的日志等级
+func (r *LevelHandler) setLevel(name string, level contract.Level, duration time.Duration) (levelState, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	leveler, ok := r.levels[name]
+	if !ok {
+		return levelState{}, false
+	}
+	//取消之前的恢复定时器，但是保留最初的日志等级
+	previous, reverting := r.previous[name]
+	if timer, ok := r.timers[name]; ok {
+		timer.Stop()
+		delete(r.timers, name)
+		delete(r.previous, name)
+	}
+	if !reverting {
+		previous = saveLevel(leveler)
+	}
+	leveler.SetLevel(level)
+	state := levelState{Name: name, Level: contract.GetNameByLevel(level)}
+	if duration > 0 {
+		var timer *time.Timer
+		timer = time.AfterFunc(duration, func() {
+			r.lock.Lock()
+			defer r.lock.Unlock()
+			//定时器已经被取消或者替换
+			if r.timers[name] != timer {
+				return
+			}
+			previous()
+			delete(r.timers, name)
+			delete(r.previous, name)
+		})
+		r.timers[name] = timer
+		r.previous[name] = previous
+		state.Revert = time.Now().Add(duration).Format(time.RFC3339)
+	}
+	return state, true
+}
+
+func (r *LevelHandler) state(name string) (levelState, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	leveler, ok := r.levels[name]
+	if !ok {
+		return levelState{}, false
+	}
+	return levelState{Name: name, Level: contract.GetNameByLevel(leveler.GetLevel())}, true
+}
+
+func (r *LevelHandler) states() []levelState {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	states := make([]levelState, 0, len(r.levels))
+	for name, leveler := range r.levels {
+		states = append(states, levelState{Name: name, Level: contract.GetNameByLevel(leveler.GetLevel())})
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Name < states[j].Name
+	})
+	return states
+}
+
+func (r *LevelHandler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (r *LevelHandler) writeError(w http.ResponseWriter, status int, message string) {
+	r.writeJSON(w, status, map[string]string{"error": message})
+}
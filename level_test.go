@@ -0,0 +1,103 @@
+package flog_test
+
+import (
+	"encoding/json"
+	"github.com/buexplain/go-flog"
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/formatter"
+	"github.com/buexplain/go-flog/handler"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAtomicLevel(t *testing.T) {
+	level := flog.NewAtomicLevel(contract.LevelError)
+	std := handler.NewSTD(contract.LevelDebug, formatter.NewLine(), contract.LevelError).SetAtomicLevel(level)
+	http := handler.NewHTTP(contract.LevelDebug, formatter.NewJSON(), "http://127.0.0.1:8106/test").SetAtomicLevel(level)
+	if std.IsHandling(contract.LevelInfo) || http.IsHandling(contract.LevelInfo) {
+		t.Error("共享的日志等级没有生效")
+	}
+	level.SetLevel(contract.LevelInfo)
+	if !std.IsHandling(contract.LevelInfo) || !http.IsHandling(contract.LevelInfo) || std.GetLevel() != contract.LevelInfo {
+		t.Error("修改共享的日志等级没有生效")
+	}
+	std.SetLevel(contract.LevelDebug)
+	if !http.IsHandling(contract.LevelDebug) {
+		t.Error("通过日志处理器修改共享的日志等级没有生效")
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	memory := newMemoryHandler(contract.LevelDebug)
+	std := handler.NewSTD(contract.LevelError, formatter.NewLine(), contract.LevelError)
+	logger := flog.New("level", std)
+	logger.PushHandler(memory)
+	levels := flog.NewLevelHandler()
+	levels.Register("level", logger)
+	levels.Register("level.std", std)
+	server := httptest.NewServer(levels)
+	defer server.Close()
+	request := func(method string, query string, body string) (int, map[string]interface{}) {
+		req, _ := http.NewRequest(method, server.URL+"?"+query, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Error("请求失败", err)
+			return 0, nil
+		}
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+		result := map[string]interface{}{}
+		_ = json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result
+	}
+	//memoryHandler 没有实现 contract.Leveler，日志收集器的日志等级取最详细的等级
+	if code, result := request(http.MethodGet, "name=level", ""); code != http.StatusOK || result["level"] != "debug" {
+		t.Error("查看日志收集器的日志等级失败", code, result)
+	}
+	if code, result := request(http.MethodGet, "name=level.std", ""); code != http.StatusOK || result["level"] != "error" {
+		t.Error("查看日志处理器的日志等级失败", code, result)
+	}
+	if code, _ := request(http.MethodGet, "name=unknown", ""); code != http.StatusNotFound {
+		t.Error("查看未注册的日志等级应该返回404", code)
+	}
+	if code, _ := request(http.MethodPut, "name=level.std&level=unknown", ""); code != http.StatusBadRequest {
+		t.Error("设置非法的日志等级应该返回400", code)
+	}
+	if code, result := request(http.MethodPut, "name=level", `{"level":"warning"}`); code != http.StatusOK || result["level"] != "warning" || std.GetLevel() != contract.LevelWarning {
+		t.Error("调整日志收集器的日志等级失败", code, result)
+	}
+	//临时调整日志等级，到期后自动恢复
+	if code, result := request(http.MethodPut, "name=level.std&level=debug&duration=50ms", ""); code != http.StatusOK || result["revert"] == nil || std.GetLevel() != contract.LevelDebug {
+		t.Error("临时调整日志处理器的日志等级失败", code, result)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for std.GetLevel() != contract.LevelWarning && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if std.GetLevel() != contract.LevelWarning {
+		t.Error("临时调整的日志等级没有恢复", std.GetLevel())
+	}
+	//临时调整日志收集器的日志等级，到期后各个日志处理器分别恢复为调整前的日志等级
+	remote := handler.NewHTTP(contract.LevelInfo, formatter.NewJSON(), "http://127.0.0.1:8106/test")
+	logger.PushHandler(remote)
+	if code, result := request(http.MethodPut, "name=level&level=debug&duration=50ms", ""); code != http.StatusOK || std.GetLevel() != contract.LevelDebug || remote.GetLevel() != contract.LevelDebug {
+		t.Error("临时调整日志收集器的日志等级失败", code, result)
+	}
+	deadline = time.Now().Add(2 * time.Second)
+	for std.GetLevel() == contract.LevelDebug && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if std.GetLevel() != contract.LevelWarning || remote.GetLevel() != contract.LevelInfo {
+		t.Error("各个日志处理器没有分别恢复为调整前的日志等级", std.GetLevel(), remote.GetLevel())
+	}
+	if code, _ := request(http.MethodDelete, "name=level", ""); code != http.StatusMethodNotAllowed {
+		t.Error("不支持的请求方法应该返回405", code)
+	}
+}
@@ -2,104 +2,126 @@ package flog
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"github.com/buexplain/go-flog/contract"
-	libLog "log"
-	"runtime/debug"
+	"github.com/buexplain/go-flog/internal/metrics"
+	"github.com/buexplain/go-flog/internal/report"
+	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Logger 日志收集齐器
 type Logger struct {
+	//各个等级被丢弃的日志数量，放在结构体开头，保证32位平台上原子操作的内存对齐
+	dropped [contract.LevelDebug + 1]uint64
 	//渠道名称
 	channel string
-	//日志处理器集合
-	handlers []contract.Handler
-	//额外日志信息处理器集合
-	extras []contract.Extra
+	//绑定的上下文信息，会写入每条日志的附加信息
+	fields map[string]interface{}
+	//根日志收集器，子日志收集器与根日志收集器共享日志处理器、额外日志信息处理器与异步日志队列
+	root *Logger
+	//绑定的调用位置，不为 nil 时替换 FuncCaller 获取的调用位置，用于调用栈深度不固定的 Recover 与 Writer
+	caller *runtime.Frame
+	//是否通过重定向写入日志，处理时产生的内部错误直接写入标准错误，避免递归
+	redirected bool
+	//日志处理器与额外日志信息处理器集合的快照，存放 *snapshot
+	snapshot atomic.Value
 	//日志收集齐器关闭状态
 	closed chan struct{}
-	//异步日志队列
-	queue chan *contract.Record
-	//异步日志队列关闭状态
-	queueClosed chan struct{}
-	//异步日志队列处理go程退出时候的等待超时时间
-	timeout time.Duration
-	//关闭锁
+	//异步日志队列容量，0表示同步调度日志
+	capacity int
+	//异步模式下是否所有日志处理器共用一个写入go程，按顺序调用日志处理器
+	chain bool
+	//异步日志队列满载时的处理配置
+	overflow Overflow
+	//异步写入go程退出时候的等待超时时间，存放 time.Duration，原子读写
+	timeout int64
+	//关闭锁，同时保证快照的修改串行进行
 	lock *sync.Mutex
+	//内部错误处理器
+	errorHandler contract.AtomicErrorHandler
+	//捕获调用栈的日志等级
+	stackLevel int32
 }
 
 func New(channel string, handler contract.Handler, extra ...contract.Extra) *Logger {
 	tmp := new(Logger)
 	tmp.channel = channel
-	tmp.handlers = []contract.Handler{}
-	tmp.PushHandler(handler)
-	tmp.extras = make([]contract.Extra, 0, len(extra))
-	tmp.extras = append(tmp.extras, extra...)
+	tmp.fields = nil
+	tmp.root = tmp
+	s := newSnapshot()
+	s.handlers = []contract.Handler{}
+	if handler != nil {
+		s.handlers = append(s.handlers, handler)
+	}
+	s.extras = make([]contract.Extra, 0, len(extra))
+	s.extras = append(s.extras, extra...)
+	s.contextExtras = []contract.ContextExtra{}
+	tmp.snapshot.Store(s)
 	tmp.closed = make(chan struct{})
-	tmp.queue = nil
-	tmp.timeout = 2 * time.Second
+	tmp.capacity = 0
+	tmp.timeout = int64(2 * time.Second)
 	tmp.lock = new(sync.Mutex)
+	tmp.stackLevel = -1
 	return tmp
 }
 
-func (r *Logger) Async(capacity int) {
-	if r.queue == nil {
-		r.queue = make(chan *contract.Record, capacity)
-		r.queueClosed = make(chan struct{})
-		//开启日志异步写入go程
-		go r.goF()
-	}
+// Async 开启异步日志，capacity 为每个日志处理器的异步日志队列容量，overflow 为队列满载时的处理配置，默认阻塞等待
+// 异步模式下每个日志处理器拥有独立的写入go程与日志队列，慢速的日志处理器不会拖累其它日志处理器，单个日志处理器内的日志顺序不变
+// 由于各个日志处理器并行处理日志，Handle 的返回值无法阻止日志进入下一个日志处理器，所有可以处理该等级的日志处理器都会收到日志
+// 需要保留 Handle 返回值阻止日志进入下一个日志处理器的语义时，使用 AsyncChain
+func (r *Logger) Async(capacity int, overflow ...Overflow) {
+	r.async(capacity, false, overflow)
 }
 
-// 日志异步写入go程
-func (r *Logger) goF() {
-	defer func() {
-		if a := recover(); a != nil {
-			//记录错误栈
-			var message string
-			message = fmt.Sprintf("Logger %s uncaught panic: %s", r.channel, debug.Stack())
-			libLog.Println(message)
-			//重启一条go程
-			go r.goF()
-		} else {
-			//正常退出，发出日志队列已经关闭信号
-			close(r.queueClosed)
-		}
-	}()
-	for {
-		select {
-		case record := <-r.queue:
-			//调度日志
-			r.dispatch(record)
-			break
-		case <-r.closed:
-			//收到日志关闭信号
-			//调度完日志队列中剩余的日志
-			for {
-				for i := len(r.queue); i > 0; i-- {
-					r.dispatch(<-r.queue)
-				}
-				if len(r.queue) == 0 {
-					break
-				}
-			}
-			//再次调度日志队列中剩余的日志，直到超时退出
-			for {
-				select {
-				case record := <-r.queue:
-					r.dispatch(record)
-				case <-time.After(r.timeout):
-					return
-				}
-			}
+// AsyncChain 开启异步日志，所有日志处理器共用一个写入go程与日志队列，capacity 为队列容量，overflow 为队列满载时的处理配置
+// 写入go程按顺序调用日志处理器，Handle 返回 true 时日志不再进入下一个日志处理器，与同步模式一致
+func (r *Logger) AsyncChain(capacity int, overflow ...Overflow) {
+	r.async(capacity, true, overflow)
+}
+
+func (r *Logger) async(capacity int, chain bool, overflow []Overflow) {
+	r = r.root
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.capacity > 0 || capacity <= 0 {
+		return
+	}
+	select {
+	case <-r.closed:
+		return
+	default:
+		break
+	}
+	if len(overflow) > 0 {
+		r.overflow = overflow[0]
+	}
+	r.capacity = capacity
+	r.chain = chain
+	s := r.load().clone()
+	s.async = true
+	if chain {
+		s.workers = []*worker{newWorker(r, newChainHandler(r, s.handlers), r.capacity)}
+	} else {
+		s.workers = make([]*worker, 0, len(s.handlers))
+		for _, handler := range s.handlers {
+			s.workers = append(s.workers, newWorker(r, handler, r.capacity))
 		}
 	}
+	r.snapshot.Store(s)
 }
 
 func (r *Logger) Close(timeout ...time.Duration) error {
+	//子日志收集器不持有日志处理器，不做关闭
+	if r.root != r {
+		return nil
+	}
+
 	//获取锁
 	r.lock.Lock()
 	defer r.lock.Unlock()
@@ -114,23 +136,44 @@ func (r *Logger) Close(timeout ...time.Duration) error {
 
 	//设置超时
 	if len(timeout) > 0 {
-		r.timeout = timeout[0]
+		atomic.StoreInt64(&r.timeout, int64(timeout[0]))
+	}
+
+	//关闭采样器前汇总一次被丢弃的日志数量
+	s := r.load()
+	if s.sampler != nil {
+		s.sampler.stop()
 	}
 
 	//发出日志关闭信号
 	close(r.closed)
 
-	//异步日志，清空日志队列中的日志
-	if r.queue != nil {
-		//等待日志队列清空
-		<-r.queueClosed
-		//关闭日志队列
-		close(r.queue)
+	//等待仍在收集日志的调用结束，此后不会再有日志抛入日志队列或者进入日志处理器
+	//所以不能在日志处理器中同步调用 Close
+	s.wait()
+
+	//异步日志，并行清空各个日志处理器队列中的日志
+	if s.async {
+		wg := &sync.WaitGroup{}
+		timeout := time.Duration(atomic.LoadInt64(&r.timeout))
+		for _, w := range s.workers {
+			wg.Add(1)
+			go func(w *worker) {
+				defer wg.Done()
+				w.stop(timeout)
+			}(w)
+		}
+		wg.Wait()
+		//写入go程已经全部退出，关闭后修改日志处理器集合不再开启新的写入go程
+		s = s.clone()
+		s.async = false
+		s.workers = nil
+		r.snapshot.Store(s)
 	}
 
 	//关闭各个日志处理器
 	bag := bytes.Buffer{}
-	for _, v := range r.handlers {
+	for _, v := range s.handlers {
 		if e := v.Close(); e != nil {
 			bag.WriteString(e.Error())
 			bag.WriteByte('\n')
@@ -144,69 +187,158 @@ func (r *Logger) Close(timeout ...time.Duration) error {
 	return nil
 }
 
+// Flush 冲刷日志但不关闭日志收集器，阻塞直到调用前收集的日志全部写入目的地或者ctx结束
+// 异步模式下会先等待各个日志处理器队列中的日志处理完毕，再冲刷实现了 contract.Flusher 的日志处理器
+func (r *Logger) Flush(ctx context.Context) error {
+	root := r.root
+	select {
+	case <-root.closed:
+		return nil
+	default:
+		break
+	}
+	bag := bytes.Buffer{}
+	s := root.load()
+	if !s.async {
+		for _, v := range s.handlers {
+			if flusher, ok := v.(contract.Flusher); ok {
+				if e := flusher.Flush(ctx); e != nil {
+					bag.WriteString(e.Error())
+					bag.WriteByte('\n')
+				}
+			}
+		}
+	} else {
+		//并行冲刷各个日志处理器
+		lock := new(sync.Mutex)
+		wg := &sync.WaitGroup{}
+		for _, w := range s.workers {
+			wg.Add(1)
+			go func(w *worker) {
+				defer wg.Done()
+				if e := w.flush(ctx); e != nil {
+					lock.Lock()
+					bag.WriteString(e.Error())
+					bag.WriteByte('\n')
+					lock.Unlock()
+				}
+			}(w)
+		}
+		wg.Wait()
+	}
+	if bag.Len() > 0 {
+		return errors.New(bag.String())
+	}
+	return nil
+}
+
 func (r *Logger) GetChannel() string {
 	return r.channel
 }
 
-func (r *Logger) PushHandler(handler contract.Handler) *Logger {
-	if handler != nil {
-		r.handlers = append(r.handlers, handler)
+// With 返回一个绑定了上下文信息的子日志收集器
+// fields 为键值对或者 contract.Field，键非字符串时会被转为字符串，落单的值以 !BADKEY 为键
+// 子日志收集器与父级共享日志处理器、额外日志信息处理器与异步日志队列，可以按请求随意创建，关闭子日志收集器不会关闭日志处理器
+func (r *Logger) With(fields ...interface{}) *Logger {
+	tmp := r.child(r.channel)
+	if len(fields) == 0 {
+		return tmp
+	}
+	if tmp.fields == nil {
+		tmp.fields = make(map[string]interface{}, len(fields)/2+1)
+	}
+	for i := 0; i < len(fields); i += 2 {
+		if field, ok := fields[i].(contract.Field); ok {
+			tmp.fields[field.Key] = field.Value()
+			i--
+			continue
+		}
+		if i+1 == len(fields) {
+			tmp.fields["!BADKEY"] = fields[i]
+			break
+		}
+		if key, ok := fields[i].(string); ok {
+			tmp.fields[key] = fields[i+1]
+		} else {
+			tmp.fields[fmt.Sprint(fields[i])] = fields[i+1]
+		}
 	}
-	return r
+	return tmp
 }
 
-func (r *Logger) PopHandler() contract.Handler {
-	if len(r.handlers) == 0 {
-		return nil
+// WithChannel 返回一个指定渠道名称的子日志收集器，子日志收集器继承父级绑定的上下文信息
+func (r *Logger) WithChannel(channel string) *Logger {
+	return r.child(channel)
+}
+
+// 创建子日志收集器，并复制父级绑定的上下文信息
+func (r *Logger) child(channel string) *Logger {
+	tmp := new(Logger)
+	tmp.channel = channel
+	tmp.root = r.root
+	if len(r.fields) > 0 {
+		tmp.fields = make(map[string]interface{}, len(r.fields))
+		for k, v := range r.fields {
+			tmp.fields[k] = v
+		}
 	}
-	tmp := r.handlers[len(r.handlers)-1]
-	r.handlers = r.handlers[0 : len(r.handlers)-1]
 	return tmp
 }
 
-func (r *Logger) GetHandlers() []contract.Handler {
-	return r.handlers
+// GetFields 返回绑定的上下文信息
+func (r *Logger) GetFields() map[string]interface{} {
+	return r.fields
+}
+
+func (r *Logger) AddRecord(level contract.Level, format bool, message string, context ...interface{}) {
+	r.addRecord(nil, level, format, message, context, nil)
 }
 
-func (r *Logger) PushExtra(extra contract.Extra) *Logger {
-	r.extras = append(r.extras, extra)
-	return r
+// LogFields 结构化字段的日志入口，字段按类型保存，格式化时无需反射
+func (r *Logger) LogFields(ctx context.Context, level contract.Level, message string, fields ...contract.Field) {
+	r.addRecord(ctx, level, false, message, nil, fields)
 }
 
-func (r *Logger) PopExtra() contract.Extra {
-	if len(r.extras) == 0 {
-		return nil
-	}
-	tmp := r.extras[len(r.extras)-1]
-	r.extras = r.extras[0 : len(r.extras)-1]
-	return tmp
+// Log 携带 context.Context 的日志入口
+func (r *Logger) Log(ctx context.Context, level contract.Level, message string, context ...interface{}) {
+	r.addRecord(ctx, level, false, message, context, nil)
 }
 
-func (r *Logger) GetExtras() []contract.Extra {
-	return r.extras
+func (r *Logger) LogF(ctx context.Context, level contract.Level, format string, v ...interface{}) {
+	r.addRecord(ctx, level, true, format, v, nil)
 }
 
-func (r *Logger) AddRecord(level contract.Level, format bool, message string, context ...interface{}) {
+// 收集日志
+// 所有公开的日志入口都必须直接调用本方法，保证调用栈深度一致，FuncCaller 才能正确获取调用者
+func (r *Logger) addRecord(ctx context.Context, level contract.Level, format bool, message string, context []interface{}, fields []contract.Field) {
+	root := r.root
+	s := root.acquire()
+	if s == nil {
+		return
+	}
+	defer s.release()
 	//判断是否有日志处理器可以处理当前level的日志
-	isHandling := false
-	for _, v := range r.handlers {
-		if v.IsHandling(level) {
-			isHandling = true
-			break
-		}
+	if !s.isHandling(level) {
+		return
 	}
-	if !isHandling {
+
+	//采样，格式化日志按格式化后的信息采样，除非采样器设置了按格式字符串采样
+	if s.sampler != nil && (!format || s.sampler.byFormat) && !s.sampler.Sample(level, message) {
 		return
 	}
 
-	//新建一个日志载体对象
-	record := contract.NewRecord()
-	record.Channel = r.channel
-	record.Level = contract.GetNameByLevel(level)
+	//从对象池获取一个日志载体对象，所有日志处理器处理完毕后归还对象池
+	record := r.newRecord(level)
+	defer record.Release()
 	if format {
-		record.Message = fmt.Sprintf(message, context...)
+		//格式化之前对参数中延迟求值的日志值求值，否则输出的是函数地址
+		record.Message = fmt.Sprintf(message, resolveArgs(context)...)
+		if s.sampler != nil && !s.sampler.byFormat && !s.sampler.Sample(level, record.Message) {
+			return
+		}
 	} else {
 		record.Message = message
+		context = splitFields(record, context)
 		if l := len(context); l > 0 {
 			if l == 1 {
 				record.Context = context[0]
@@ -215,12 +347,85 @@ func (r *Logger) AddRecord(level contract.Level, format bool, message string, co
 			}
 		}
 	}
+	if len(fields) > 0 {
+		record.Fields = append(record.Fields, fields...)
+	}
 
 	//给日志对象添加额外信息
-	for _, v := range r.extras {
+	for _, v := range s.extras {
 		v.Processor(record)
 	}
+	if ctx != nil {
+		for _, v := range s.contextExtras {
+			v.ContextProcessor(ctx, record)
+		}
+	}
+	//跳过 addRecord 与公开的日志方法
+	r.process(s, record, level, r.caller, 2)
+}
+
+// 额外日志信息处理器执行之后的公共流程，addRecord 与 SlogHandler.Handle 共用
+// 额外日志信息处理器依赖调用栈深度获取调用位置，所以由调用方直接执行
+// caller 不为 nil 时替换 FuncCaller 获取的调用位置，skip 是包括 process 的调用方在内，到业务代码之前的栈帧数，用于捕获调用栈
+func (r *Logger) process(s *snapshot, record *contract.Record, level contract.Level, caller *runtime.Frame, skip int) {
+	if _, ok := record.Extra["File"]; ok && caller != nil {
+		record.Extra["File"] = caller.File
+		record.Extra["Line"] = caller.Line
+	}
 
+	//对延迟求值的日志值求值，包括额外日志信息处理器添加的，此时已经确认有日志处理器处理该等级的日志，并且尚未进入异步队列
+	resolveValues(record)
+
+	//将 error 转换为结构化的错误，按日志等级捕获调用栈
+	if hasErrors(record) {
+		stack := ""
+		if level <= contract.Level(atomic.LoadInt32(&r.root.stackLevel)) {
+			stack = captureStack(skip)
+		}
+		convertErrors(record, stack)
+	}
+
+	//过滤日志
+	if !s.filter(record) {
+		return
+	}
+
+	r.root.emit(s, record, level)
+}
+
+// 返回调用栈中第一个不属于 skip 前缀的函数的调用位置，depth 是 callerFrame 的调用方需要跳过的调用栈深度
+func callerFrame(depth int, skip string) *runtime.Frame {
+	var pcs [16]uintptr
+	//跳过 runtime.Callers 与 callerFrame
+	n := runtime.Callers(depth+2, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, skip) {
+			return &frame
+		}
+		if !more {
+			return nil
+		}
+	}
+}
+
+// 从对象池获取日志载体对象，并写入渠道、等级与绑定的上下文信息
+func (r *Logger) newRecord(level contract.Level) *contract.Record {
+	record := contract.AcquireRecord()
+	record.Channel = r.channel
+	record.Level = contract.GetNameByLevel(level)
+	for k, v := range r.fields {
+		record.Extra[k] = v
+	}
+	if r.redirected {
+		record.DisableReflux()
+	}
+	return record
+}
+
+// 调度已经添加完额外信息的日志，r 必须是根日志收集器
+func (r *Logger) emit(s *snapshot, record *contract.Record, level contract.Level) {
 	//判断日志收集齐器状态
 	select {
 	case <-r.closed:
@@ -231,26 +436,56 @@ func (r *Logger) AddRecord(level contract.Level, format bool, message string, co
 		break
 	}
 
+	metrics.Records.Add(record.Channel, int(level), 1)
+
 	//调度日志
-	if r.queue == nil {
+	if !s.async {
 		//同步调度
-		r.dispatch(record)
+		r.dispatch(s.handlers, record)
 	} else {
-		//异步抛入日志队列
-		r.queue <- record
+		//异步抛入各个日志处理器的日志队列，每个队列各自持有一份引用
+		for _, w := range s.workers {
+			if w.handler.IsHandling(level) {
+				w.enqueue(record.Retain(), level)
+			}
+		}
+	}
+}
+
+// 将上下文中的结构化字段移入日志的结构化字段，返回剩余的上下文
+func splitFields(record *contract.Record, context []interface{}) []interface{} {
+	n := 0
+	for _, v := range context {
+		if _, ok := v.(contract.Field); ok {
+			n++
+		}
+	}
+	if n == 0 {
+		return context
+	}
+	rest := make([]interface{}, 0, len(context)-n)
+	for _, v := range context {
+		if field, ok := v.(contract.Field); ok {
+			record.Fields = append(record.Fields, field)
+		} else {
+			rest = append(rest, v)
+		}
 	}
+	return rest
 }
 
-func (r *Logger) dispatch(record *contract.Record) {
+func (r *Logger) dispatch(handlers []contract.Handler, record *contract.Record) {
+	var current contract.Handler
 	defer func() {
 		//捕获所有异常，即便日志崩溃，也不影响进程
 		err := recover()
 		if err != nil {
-			libLog.Println(err)
+			r.handleError(&contract.Error{Handler: report.Name(current), Kind: contract.ErrorKindPanic, Record: record, Err: report.Panic(err)})
 		}
 	}()
-	for _, v := range r.handlers {
+	for _, v := range handlers {
 		if v.IsHandling(contract.GetLevelByName(record.Level)) {
+			current = v
 			if v.Handle(record) {
 				break
 			}
@@ -260,72 +495,104 @@ func (r *Logger) dispatch(record *contract.Record) {
 
 // Emergency 紧急情况：系统无法使用
 func (r *Logger) Emergency(message string, context ...interface{}) {
-	r.AddRecord(contract.LevelEmergency, false, message, context...)
+	r.addRecord(nil, contract.LevelEmergency, false, message, context, nil)
 }
 
 func (r *Logger) EmergencyF(format string, v ...interface{}) {
-	r.AddRecord(contract.LevelEmergency, true, format, v...)
+	r.addRecord(nil, contract.LevelEmergency, true, format, v, nil)
+}
+
+func (r *Logger) EmergencyCtx(ctx context.Context, message string, context ...interface{}) {
+	r.addRecord(ctx, contract.LevelEmergency, false, message, context, nil)
 }
 
 // Alert 警报：必须立即采取措施
 func (r *Logger) Alert(message string, context ...interface{}) {
-	r.AddRecord(contract.LevelAlert, false, message, context...)
+	r.addRecord(nil, contract.LevelAlert, false, message, context, nil)
 }
 
 func (r *Logger) AlertF(format string, v ...interface{}) {
-	r.AddRecord(contract.LevelAlert, true, format, v...)
+	r.addRecord(nil, contract.LevelAlert, true, format, v, nil)
+}
+
+func (r *Logger) AlertCtx(ctx context.Context, message string, context ...interface{}) {
+	r.addRecord(ctx, contract.LevelAlert, false, message, context, nil)
 }
 
 // Critical 严重：危急情况
 func (r *Logger) Critical(message string, context ...interface{}) {
-	r.AddRecord(contract.LevelCritical, false, message, context...)
+	r.addRecord(nil, contract.LevelCritical, false, message, context, nil)
 }
 
 func (r *Logger) CriticalF(format string, v ...interface{}) {
-	r.AddRecord(contract.LevelCritical, true, format, v...)
+	r.addRecord(nil, contract.LevelCritical, true, format, v, nil)
+}
+
+func (r *Logger) CriticalCtx(ctx context.Context, message string, context ...interface{}) {
+	r.addRecord(ctx, contract.LevelCritical, false, message, context, nil)
 }
 
 // 错误
 func (r *Logger) Error(message string, context ...interface{}) {
-	r.AddRecord(contract.LevelError, false, message, context...)
+	r.addRecord(nil, contract.LevelError, false, message, context, nil)
 }
 
 func (r *Logger) ErrorF(format string, v ...interface{}) {
-	r.AddRecord(contract.LevelError, true, format, v...)
+	r.addRecord(nil, contract.LevelError, true, format, v, nil)
+}
+
+func (r *Logger) ErrorCtx(ctx context.Context, message string, context ...interface{}) {
+	r.addRecord(ctx, contract.LevelError, false, message, context, nil)
 }
 
 // Warning 警告
 func (r *Logger) Warning(message string, context ...interface{}) {
-	r.AddRecord(contract.LevelWarning, false, message, context...)
+	r.addRecord(nil, contract.LevelWarning, false, message, context, nil)
 }
 
 func (r *Logger) WarningF(format string, v ...interface{}) {
-	r.AddRecord(contract.LevelWarning, true, format, v...)
+	r.addRecord(nil, contract.LevelWarning, true, format, v, nil)
+}
+
+func (r *Logger) WarningCtx(ctx context.Context, message string, context ...interface{}) {
+	r.addRecord(ctx, contract.LevelWarning, false, message, context, nil)
 }
 
 // Notice 注意：正常但重要条件
 func (r *Logger) Notice(message string, context ...interface{}) {
-	r.AddRecord(contract.LevelNotice, false, message, context...)
+	r.addRecord(nil, contract.LevelNotice, false, message, context, nil)
 }
 
 func (r *Logger) NoticeF(format string, v ...interface{}) {
-	r.AddRecord(contract.LevelNotice, true, format, v...)
+	r.addRecord(nil, contract.LevelNotice, true, format, v, nil)
+}
+
+func (r *Logger) NoticeCtx(ctx context.Context, message string, context ...interface{}) {
+	r.addRecord(ctx, contract.LevelNotice, false, message, context, nil)
 }
 
 // Info 信息
 func (r *Logger) Info(message string, context ...interface{}) {
-	r.AddRecord(contract.LevelInfo, false, message, context...)
+	r.addRecord(nil, contract.LevelInfo, false, message, context, nil)
 }
 
 func (r *Logger) InfoF(format string, v ...interface{}) {
-	r.AddRecord(contract.LevelInfo, true, format, v...)
+	r.addRecord(nil, contract.LevelInfo, true, format, v, nil)
+}
+
+func (r *Logger) InfoCtx(ctx context.Context, message string, context ...interface{}) {
+	r.addRecord(ctx, contract.LevelInfo, false, message, context, nil)
 }
 
 // Debug 调试
 func (r *Logger) Debug(message string, context ...interface{}) {
-	r.AddRecord(contract.LevelDebug, false, message, context...)
+	r.addRecord(nil, contract.LevelDebug, false, message, context, nil)
 }
 
 func (r *Logger) DebugF(format string, v ...interface{}) {
-	r.AddRecord(contract.LevelDebug, true, format, v...)
+	r.addRecord(nil, contract.LevelDebug, true, format, v, nil)
+}
+
+func (r *Logger) DebugCtx(ctx context.Context, message string, context ...interface{}) {
+	r.addRecord(ctx, contract.LevelDebug, false, message, context, nil)
 }
@@ -1,6 +1,7 @@
 package flog_test
 
 import (
+	"context"
 	"fmt"
 	"github.com/buexplain/go-flog"
 	"github.com/buexplain/go-flog/contract"
@@ -8,6 +9,8 @@ import (
 	"github.com/buexplain/go-flog/formatter"
 	"github.com/buexplain/go-flog/handler"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -92,4 +95,569 @@ func TestLogger(t *testing.T) {
 	if err := os.RemoveAll(path+"loggerAsyncFileAwait"); err != nil {
 		t.Error("日志处理器的文件未关闭 loggerAsyncFileAwait：", err)
 	}
-}
\ No newline at end of file
+}
+// 内存日志处理器，收集日志到切片中，便于断言
+type memoryHandler struct {
+	lock    *sync.Mutex
+	level   contract.Level
+	records []*contract.Record
+	closed  int
+	flushed int
+}
+
+func newMemoryHandler(level contract.Level) *memoryHandler {
+	return &memoryHandler{lock: new(sync.Mutex), level: level}
+}
+
+func (r *memoryHandler) Handle(record *contract.Record) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	//收集的日志在 Handle 返回后仍被持有，不能被回收
+	r.records = append(r.records, record.Retain())
+	return false
+}
+
+func (r *memoryHandler) IsHandling(level contract.Level) bool {
+	return level <= r.level
+}
+
+func (r *memoryHandler) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.closed++
+	return nil
+}
+
+func (r *memoryHandler) Flush(ctx context.Context) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.flushed++
+	return nil
+}
+
+func (r *memoryHandler) getRecords() []*contract.Record {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	tmp := make([]*contract.Record, len(r.records))
+	copy(tmp, r.records)
+	return tmp
+}
+
+func TestLoggerWith(t *testing.T) {
+	memory := newMemoryHandler(contract.LevelDebug)
+	logger := flog.New("parent", memory)
+	child := logger.With("requestID", "abc", "userID", 100)
+	grandson := child.WithChannel("grandson").With("step", 1, "odd")
+	logger.Info("parent")
+	child.Info("child")
+	grandson.Info("grandson")
+	records := memory.getRecords()
+	if len(records) != 3 {
+		t.Errorf("期待收集到 3 条日志，实际收集到 %d 条", len(records))
+		return
+	}
+	if len(records[0].Extra) != 0 {
+		t.Error("父日志收集器不应该携带子日志收集器绑定的上下文信息", records[0].Extra)
+	}
+	if records[1].Channel != "parent" || records[1].Extra["requestID"] != "abc" || records[1].Extra["userID"] != 100 {
+		t.Error("子日志收集器绑定的上下文信息错误", records[1].Channel, records[1].Extra)
+	}
+	if records[2].Channel != "grandson" || records[2].Extra["requestID"] != "abc" || records[2].Extra["step"] != 1 || records[2].Extra["!BADKEY"] != "odd" {
+		t.Error("孙日志收集器绑定的上下文信息错误", records[2].Channel, records[2].Extra)
+	}
+	if len(child.GetFields()) != 2 {
+		t.Error("子日志收集器的上下文信息被孙日志收集器修改", child.GetFields())
+	}
+	//关闭子日志收集器，不会关闭共享的日志处理器
+	if err := child.Close(); err != nil {
+		t.Error("关闭子日志收集器失败", err)
+	}
+	child.Info("child after close")
+	if memory.closed != 0 || len(memory.getRecords()) != 4 {
+		t.Error("关闭子日志收集器不应该关闭日志处理器")
+	}
+	//关闭父日志收集器，子日志收集器不再收集日志
+	if err := logger.Close(); err != nil {
+		t.Error("关闭日志收集器失败", err)
+	}
+	child.Info("child after parent close")
+	if memory.closed != 1 || len(memory.getRecords()) != 4 {
+		t.Error("关闭父日志收集器后，子日志收集器不应该继续收集日志")
+	}
+}
+
+func TestLoggerCtx(t *testing.T) {
+	memory := newMemoryHandler(contract.LevelDebug)
+	logger := flog.New("ctx", memory, extra.NewFuncCaller())
+	logger.PushContextExtra(extra.NewRequestID())
+	logger.PushContextExtra(extra.NewTraceParent())
+	var _ contract.Logger = logger
+	ctx := extra.WithRequestID(context.Background(), "req-1")
+	ctx = extra.WithTraceParent(ctx, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	logger.InfoCtx(ctx, "info")
+	logger.LogF(ctx, contract.LevelError, "error %d", 1)
+	logger.With("a", 1).Log(ctx, contract.LevelDebug, "debug")
+	logger.Info("without ctx")
+	records := memory.getRecords()
+	if len(records) != 4 {
+		t.Errorf("期待收集到 4 条日志，实际收集到 %d 条", len(records))
+		return
+	}
+	for i, record := range records[0:3] {
+		if record.Extra["RequestID"] != "req-1" || record.Extra["TraceID"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Error("从context提取额外信息失败", i, record.Extra)
+		}
+		if file, ok := record.Extra["File"].(string); !ok || !strings.HasSuffix(file, "logger_test.go") {
+			t.Error("获取日志调用者失败", i, record.Extra)
+		}
+	}
+	if records[1].Message != "error 1" || records[1].Level != "error" {
+		t.Error("格式化日志失败", records[1].Message, records[1].Level)
+	}
+	if _, ok := records[3].Extra["RequestID"]; ok {
+		t.Error("没有context时不应该提取额外信息")
+	}
+	if file, ok := records[3].Extra["File"].(string); !ok || !strings.HasSuffix(file, "logger_test.go") {
+		t.Error("获取日志调用者失败", records[3].Extra)
+	}
+}
+
+func TestLoggerFields(t *testing.T) {
+	memory := newMemoryHandler(contract.LevelDebug)
+	logger := flog.New("fields", memory)
+	logger.LogFields(nil, contract.LevelInfo, "typed", flog.String("a", "b"), flog.Int("c", 1))
+	logger.Info("mixed", flog.String("a", "b"), "context")
+	logger.With(flog.String("bound", "value"), "k", "v").Info("bound")
+	records := memory.getRecords()
+	if len(records) != 3 {
+		t.Errorf("期待收集到 3 条日志，实际收集到 %d 条", len(records))
+		return
+	}
+	if len(records[0].Fields) != 2 || records[0].Fields[1].Integer != 1 || records[0].Context != nil {
+		t.Error("结构化字段收集错误", records[0].Fields, records[0].Context)
+	}
+	if len(records[1].Fields) != 1 || records[1].Fields[0].String != "b" || records[1].Context != "context" {
+		t.Error("上下文中的结构化字段收集错误", records[1].Fields, records[1].Context)
+	}
+	if records[2].Extra["bound"] != "value" || records[2].Extra["k"] != "v" {
+		t.Error("绑定结构化字段错误", records[2].Extra)
+	}
+}
+
+// 阻塞的日志处理器，收到第一条日志后阻塞，直到放行
+type gateHandler struct {
+	*memoryHandler
+	entered chan struct{}
+	gate    chan struct{}
+	once    *sync.Once
+}
+
+func newGateHandler() *gateHandler {
+	return &gateHandler{memoryHandler: newMemoryHandler(contract.LevelDebug), entered: make(chan struct{}), gate: make(chan struct{}), once: new(sync.Once)}
+}
+
+func (r *gateHandler) Handle(record *contract.Record) bool {
+	r.once.Do(func() {
+		close(r.entered)
+		<-r.gate
+	})
+	return r.memoryHandler.Handle(record)
+}
+
+func TestLoggerOverflow(t *testing.T) {
+	cases := []struct {
+		overflow flog.Overflow
+		dropped  uint64
+		messages []string
+	}{
+		{flog.Overflow{Policy: flog.OverflowDropNewest}, 2, []string{"1", "2", "3", "2 records dropped by async queue overflow"}},
+		{flog.Overflow{Policy: flog.OverflowDropOldest}, 2, []string{"1", "4", "5", "2 records dropped by async queue overflow"}},
+		{flog.Overflow{Policy: flog.OverflowBlockTimeout, Timeout: 10 * time.Millisecond}, 2, []string{"1", "2", "3", "2 records dropped by async queue overflow"}},
+		{flog.Overflow{Policy: flog.OverflowDropBelow, Level: contract.LevelError}, 2, []string{"1", "2", "3", "2 records dropped by async queue overflow"}},
+	}
+	for i, c := range cases {
+		gate := newGateHandler()
+		logger := flog.New("overflow", gate)
+		logger.Async(2, c.overflow)
+		logger.Info("1")
+		<-gate.entered
+		for _, message := range []string{"2", "3", "4", "5"} {
+			logger.Info(message)
+		}
+		if logger.Dropped() != c.dropped || logger.DroppedByLevel(contract.LevelInfo) != c.dropped {
+			t.Errorf("用例 %d 期待丢弃 %d 条日志，实际丢弃 %d 条", i, c.dropped, logger.Dropped())
+		}
+		close(gate.gate)
+		if err := logger.Close(100 * time.Millisecond); err != nil {
+			t.Error("关闭日志收集器失败", err)
+		}
+		records := gate.getRecords()
+		messages := make([]string, 0, len(records))
+		for _, record := range records {
+			messages = append(messages, record.Message)
+		}
+		if fmt.Sprint(messages) != fmt.Sprint(c.messages) {
+			t.Errorf("用例 %d 期待收集到日志 %v，实际收集到 %v", i, c.messages, messages)
+		}
+	}
+}
+
+func TestLoggerAsyncWorkers(t *testing.T) {
+	slow := newGateHandler()
+	fast := newMemoryHandler(contract.LevelDebug)
+	logger := flog.New("workers", slow)
+	logger.PushHandler(fast)
+	logger.Async(100)
+	total := 50
+	for i := 0; i < total; i++ {
+		logger.Info(strconv.Itoa(i))
+	}
+	<-slow.entered
+	//慢速的日志处理器阻塞时，其它日志处理器继续处理日志
+	deadline := time.Now().Add(5 * time.Second)
+	for len(fast.getRecords()) != total && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := len(fast.getRecords()); n != total {
+		t.Errorf("慢速的日志处理器阻塞了其它日志处理器，期待收集到 %d 条日志，实际收集到 %d 条", total, n)
+	}
+	//异步模式下新增的日志处理器也拥有独立的写入go程
+	pushed := newMemoryHandler(contract.LevelDebug)
+	logger.PushHandler(pushed)
+	logger.Info("pushed")
+	go func() {
+		<-time.After(100 * time.Millisecond)
+		close(slow.gate)
+	}()
+	if err := logger.Close(); err != nil {
+		t.Error("关闭日志收集器失败", err)
+	}
+	for _, handler := range []*memoryHandler{slow.memoryHandler, fast} {
+		records := handler.getRecords()
+		if len(records) != total+1 || handler.closed != 1 {
+			t.Errorf("关闭日志收集器时没有处理完队列中的日志，期待收集到 %d 条日志，实际收集到 %d 条", total+1, len(records))
+			continue
+		}
+		for i := 0; i < total; i++ {
+			if records[i].Message != strconv.Itoa(i) {
+				t.Error("日志处理器内的日志顺序错误", i, records[i].Message)
+				break
+			}
+		}
+	}
+	if records := pushed.getRecords(); len(records) != 1 || records[0].Message != "pushed" {
+		t.Error("异步模式下新增的日志处理器没有收到日志")
+	}
+}
+
+// 阻止日志进入下一个日志处理器的内存日志处理器
+type stopHandler struct {
+	*memoryHandler
+}
+
+func (r *stopHandler) Handle(record *contract.Record) bool {
+	r.memoryHandler.Handle(record)
+	return true
+}
+
+func TestLoggerAsyncBubble(t *testing.T) {
+	for _, chain := range []bool{false, true} {
+		first := &stopHandler{memoryHandler: newMemoryHandler(contract.LevelWarning)}
+		second := newMemoryHandler(contract.LevelDebug)
+		logger := flog.New("bubble", first)
+		logger.PushHandler(second)
+		if chain {
+			logger.AsyncChain(10)
+		} else {
+			logger.Async(10)
+		}
+		logger.Error("error")
+		logger.Info("info")
+		//共用写入go程时，修改日志处理器集合后仍然按顺序调用日志处理器
+		pushed := newMemoryHandler(contract.LevelDebug)
+		logger.PushHandler(pushed)
+		logger.Error("pushed")
+		if err := logger.Flush(context.Background()); err != nil || second.flushed != 1 {
+			t.Error("冲刷日志失败", chain, err)
+		}
+		if err := logger.Close(10 * time.Millisecond); err != nil {
+			t.Error(err)
+		}
+		if len(first.getRecords()) != 2 {
+			t.Error("第一个日志处理器收集的日志错误", chain, len(first.getRecords()))
+		}
+		got := messages(second.getRecords())
+		if chain {
+			//Handle 返回 true 的日志不再进入下一个日志处理器
+			if len(got) != 1 || got[0] != "info" || len(pushed.getRecords()) != 0 {
+				t.Error("AsyncChain 应该保留 Handle 返回值的语义", got, len(pushed.getRecords()))
+			}
+		} else if len(got) != 3 || len(pushed.getRecords()) != 1 {
+			//各个日志处理器独立处理日志，所有可以处理该等级的日志处理器都会收到日志
+			t.Error("Async 应该将日志交给所有可以处理该等级的日志处理器", got)
+		}
+	}
+}
+
+// 阻塞的额外日志信息处理器，用于模拟正在收集的日志
+type blockingExtra struct {
+	entered chan struct{}
+	gate    chan struct{}
+	once    *sync.Once
+}
+
+func (r *blockingExtra) Processor(record *contract.Record) {
+	r.once.Do(func() {
+		close(r.entered)
+		<-r.gate
+	})
+}
+
+func TestLoggerCloseWaitsInFlight(t *testing.T) {
+	memory := newMemoryHandler(contract.LevelDebug)
+	extra := &blockingExtra{entered: make(chan struct{}), gate: make(chan struct{}), once: new(sync.Once)}
+	logger := flog.New("inflight", memory, extra)
+	logger.Async(10)
+	go logger.Info("inflight")
+	<-extra.entered
+	closed := make(chan error, 1)
+	go func() {
+		closed <- logger.Close(10 * time.Millisecond)
+	}()
+	//正在收集日志时，Close 等待收集结束后才停止写入go程
+	select {
+	case <-closed:
+		t.Fatal("Close 没有等待正在收集的日志")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(extra.gate)
+	if err := <-closed; err != nil {
+		t.Error(err)
+	}
+	if memory.closed != 1 {
+		t.Error("关闭日志收集器失败")
+	}
+}
+
+func TestLoggerCloseUnderLoad(t *testing.T) {
+	logger := flog.New("load", newMemoryHandler(contract.LevelDebug))
+	stop := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					logger.Info("load")
+				}
+			}
+		}()
+	}
+	defer func() {
+		close(stop)
+		wg.Wait()
+	}()
+	time.Sleep(10 * time.Millisecond)
+	//持续有日志写入时，Close 拒绝新的日志，不会一直等待
+	closed := make(chan error, 1)
+	go func() {
+		closed <- logger.Close()
+	}()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("持续有日志写入时 Close 没有返回")
+	}
+}
+
+func TestLoggerFlush(t *testing.T) {
+	//同步模式
+	memory := newMemoryHandler(contract.LevelDebug)
+	logger := flog.New("flush", memory)
+	logger.Info("sync")
+	if err := logger.Flush(context.Background()); err != nil || memory.flushed != 1 {
+		t.Error("同步模式冲刷日志失败", err)
+	}
+	//异步模式，冲刷时等待队列中的日志处理完毕
+	slow := newGateHandler()
+	logger = flog.New("flush", slow)
+	logger.Async(100)
+	for i := 0; i < 10; i++ {
+		logger.Info(strconv.Itoa(i))
+	}
+	<-slow.entered
+	//日志处理器阻塞时，冲刷超时
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := logger.Flush(ctx); err == nil {
+		t.Error("日志处理器阻塞时，冲刷日志应该超时")
+	}
+	close(slow.gate)
+	if err := logger.Flush(context.Background()); err != nil {
+		t.Error("异步模式冲刷日志失败", err)
+	}
+	if n := len(slow.getRecords()); n != 10 || slow.flushed != 1 {
+		t.Errorf("冲刷日志后，期待收集到 10 条日志，实际收集到 %d 条", n)
+	}
+	if slow.closed != 0 {
+		t.Error("冲刷日志不应该关闭日志处理器")
+	}
+	logger.Info("after flush")
+	if err := logger.Close(10 * time.Millisecond); err != nil {
+		t.Error("关闭日志收集器失败", err)
+	}
+}
+
+// 测试并发修改日志处理器与额外日志信息处理器，需要配合 go test -race 运行
+func TestLoggerConcurrentReconfigure(t *testing.T) {
+	for _, async := range []bool{false, true} {
+		base := newMemoryHandler(contract.LevelDebug)
+		logger := flog.New("reconfigure", base)
+		if async {
+			logger.Async(100)
+		}
+		stop := make(chan struct{})
+		wg := &sync.WaitGroup{}
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					logger.Info("message", "context")
+					select {
+					case <-stop:
+						return
+					default:
+						break
+					}
+				}
+			}()
+		}
+		for i := 0; i < 100; i++ {
+			extra := newMemoryHandler(contract.LevelInfo)
+			logger.PushHandler(extra)
+			logger.InsertHandler(0, newMemoryHandler(contract.LevelError))
+			n := i
+			logger.PushExtra(flogExtraFunc(func(record *contract.Record) {
+				record.Extra["i"] = n
+			}))
+			_ = logger.GetHandlers()
+			if !logger.RemoveHandler(extra) {
+				t.Error("移除日志处理器失败")
+			}
+			logger.PopExtra()
+		}
+		removed := logger.SetHandlers(base)
+		close(stop)
+		wg.Wait()
+		if len(removed) != 100 || len(logger.GetHandlers()) != 1 || len(logger.GetExtras()) != 0 {
+			t.Error("替换日志处理器失败", len(removed), len(logger.GetHandlers()))
+		}
+		if logger.RemoveHandler(newMemoryHandler(contract.LevelDebug)) {
+			t.Error("移除不存在的日志处理器应该返回false")
+		}
+		if err := logger.Close(10 * time.Millisecond); err != nil {
+			t.Error("关闭日志收集器失败", err)
+		}
+		if len(base.getRecords()) == 0 {
+			t.Error("修改日志处理器期间没有收集到日志")
+		}
+	}
+}
+
+func TestLoggerSwap(t *testing.T) {
+	old := newGateHandler()
+	logger := flog.New("swap", old)
+	logger.Async(10)
+	logger.Info("1")
+	<-old.entered
+	logger.Info("2")
+	logger.Info("3")
+	//替换期间旧日志处理器仍然阻塞，放行后队列中的日志处理完毕才会被关闭
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(old.gate)
+	}()
+	current := newMemoryHandler(contract.LevelDebug)
+	extra := flogExtraFunc(func(record *contract.Record) {
+		record.Extra["swapped"] = true
+	})
+	if err := logger.Swap([]contract.Handler{current}, []contract.Extra{extra}, nil); err != nil {
+		t.Error("替换日志处理器失败", err)
+	}
+	if len(old.getRecords()) != 3 || old.closed != 1 {
+		t.Error("旧日志处理器没有处理完队列中的日志后关闭", len(old.getRecords()), old.closed)
+	}
+	logger.Info("4")
+	if err := logger.Close(10 * time.Millisecond); err != nil {
+		t.Error("关闭日志收集器失败", err)
+	}
+	records := current.getRecords()
+	if len(records) != 1 || records[0].Message != "4" || records[0].Extra["swapped"] != true {
+		t.Error("新日志处理器或者额外日志信息处理器没有生效", records)
+	}
+	if err := logger.Swap([]contract.Handler{newMemoryHandler(contract.LevelDebug)}, nil, nil); err == nil {
+		t.Error("关闭后替换日志处理器应该返回错误")
+	}
+	if len(logger.GetHandlers()) != 1 || logger.GetHandlers()[0] != current {
+		t.Error("关闭后不应该替换日志处理器")
+	}
+}
+
+// 函数形式的额外日志信息处理器
+type flogExtraFunc func(record *contract.Record)
+
+func (r flogExtraFunc) Processor(record *contract.Record) {
+	r(record)
+}
+
+func TestLoggerRecordPool(t *testing.T) {
+	for _, async := range []bool{false, true} {
+		memory := newMemoryHandler(contract.LevelDebug)
+		root := flog.New("pool", memory)
+		root.PushHandler(&discardHandler{formatter: formatter.NewJSON()})
+		if async {
+			root.Async(10)
+		}
+		logger := root.With("bound", "value")
+		total := 200
+		for i := 0; i < total; i++ {
+			logger.Info(strconv.Itoa(i), flog.Int("i", i))
+		}
+		if err := root.Flush(context.Background()); err != nil {
+			t.Error("冲刷日志失败", err)
+		}
+		//持有引用的日志不会被其它日志复用
+		records := memory.getRecords()
+		if len(records) != total {
+			t.Errorf("期待收集到 %d 条日志，实际收集到 %d 条", total, len(records))
+			continue
+		}
+		for i, record := range records {
+			if record.Message != strconv.Itoa(i) || len(record.Fields) != 1 || record.Fields[0].Integer != int64(i) || record.Extra["bound"] != "value" {
+				t.Errorf("被持有的日志被回收复用 %d %+v", i, record)
+				break
+			}
+			record.Release()
+		}
+		//重复释放引用会引发恐慌
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("重复释放日志引用应该引发恐慌")
+				}
+			}()
+			record := contract.AcquireRecord()
+			record.Release()
+			record.Release()
+		}()
+		_ = root.Close(10 * time.Millisecond)
+	}
+}
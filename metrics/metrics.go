@@ -0,0 +1,45 @@
+// Package metrics 对外暴露日志库自身的运行指标，用于观察日志是否健康
+// 导入本包时指标以 flog 为名发布到 expvar，Handler 以 Prometheus 文本格式输出指标，无需依赖 Prometheus 客户端库
+//
+// 指标包括：
+// flog_records_total 各个通道、各个等级进入日志处理器的日志数量
+// flog_async_dropped_total 各个通道、各个等级被异步日志队列丢弃的日志数量
+// flog_handler_errors_total 各个日志处理器、各个类型的内部错误数量
+// flog_format_errors_total 各个格式化处理器格式化失败的日志数量
+// flog_file_written_bytes_total 各个文件日志处理器写入的字节数
+// flog_http_request_duration_seconds 各个http接口日志处理器的请求耗时
+// flog_dingtalk_deduplicated_total 各个钉钉日志处理器压缩掉的重复日志数量
+// 日志处理器以名称区分，同名的日志处理器共享指标
+package metrics
+
+import (
+	"expvar"
+	"github.com/buexplain/go-flog/internal/metrics"
+	"io"
+	"net/http"
+)
+
+func init() {
+	expvar.Publish("flog", expvar.Func(func() interface{} {
+		return Snapshot()
+	}))
+}
+
+// Snapshot 返回全部指标的快照，以指标名称为键
+func Snapshot() map[string]interface{} {
+	return metrics.Snapshot()
+}
+
+// WriteText 以 Prometheus 文本格式输出全部指标
+func WriteText(w io.Writer) error {
+	return metrics.WriteText(w)
+}
+
+// Handler 返回以 Prometheus 文本格式输出全部指标的 http.Handler
+// 用法：http.Handle("/metrics", metrics.Handler())
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = WriteText(w)
+	})
+}
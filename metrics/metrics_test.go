@@ -0,0 +1,78 @@
+package metrics_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"github.com/buexplain/go-flog"
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/formatter"
+	"github.com/buexplain/go-flog/handler"
+	"github.com/buexplain/go-flog/metrics"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	file := handler.NewFile(contract.LevelDebug, formatter.NewJSON(), t.TempDir())
+	file.SetName("metricsFile")
+	file.SetBubble(false)
+	remote := handler.NewHTTP(contract.LevelError, formatter.NewLine(), server.URL).SetName("metricsHTTP").SetBubble(false)
+	logger := flog.New("metrics", remote)
+	logger.PushHandler(file)
+	logger.Info("info")
+	logger.Info("info")
+	logger.Error("error")
+	//无法编码为json的上下文
+	logger.Warning("format", func() {})
+	if err := logger.Close(); err != nil {
+		t.Error(err)
+	}
+
+	recorder := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Error("Prometheus 文本格式的内容类型错误", recorder.Header().Get("Content-Type"))
+	}
+	text := recorder.Body.String()
+	for _, v := range []string{
+		`# TYPE flog_records_total counter`,
+		`flog_records_total{channel="metrics",level="info"} 2`,
+		`flog_records_total{channel="metrics",level="error"} 1`,
+		`flog_handler_errors_total{handler="metricsHTTP",kind="request"} 1`,
+		`flog_handler_errors_total{handler="metricsFile",kind="write"} 1`,
+		`flog_format_errors_total{formatter="json"}`,
+		`flog_file_written_bytes_total{handler="metricsFile"}`,
+		`# TYPE flog_http_request_duration_seconds histogram`,
+		`flog_http_request_duration_seconds_bucket{handler="metricsHTTP",le="+Inf"} 1`,
+		`flog_http_request_duration_seconds_count{handler="metricsHTTP"} 1`,
+	} {
+		if !strings.Contains(text, v) {
+			t.Error("Prometheus 文本格式缺少指标", v)
+		}
+	}
+	if t.Failed() {
+		t.Log(text)
+	}
+
+	//expvar 发布的指标
+	v := expvar.Get("flog")
+	if v == nil {
+		t.Fatal("指标没有发布到 expvar")
+	}
+	snapshot := map[string]map[string]interface{}{}
+	if err := json.Unmarshal([]byte(v.String()), &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if records, ok := snapshot["flog_records_total"]["metrics"].(map[string]interface{}); !ok || records["info"] != float64(2) {
+		t.Error("expvar 中的日志数量错误", snapshot["flog_records_total"])
+	}
+	if _, ok := snapshot["flog_file_written_bytes_total"]["metricsFile"]; !ok {
+		t.Error("expvar 中缺少文件写入字节数", snapshot["flog_file_written_bytes_total"])
+	}
+}
@@ -0,0 +1,58 @@
+package flog
+
+import (
+	"github.com/buexplain/go-flog/contract"
+	"sync/atomic"
+	"time"
+)
+
+// OverflowPolicy 异步日志队列满载时的处理策略
+type OverflowPolicy int
+
+const (
+	// OverflowBlock 阻塞等待队列空闲
+	OverflowBlock OverflowPolicy = iota
+	// OverflowBlockTimeout 阻塞等待队列空闲，超时后丢弃当前日志
+	OverflowBlockTimeout
+	// OverflowDropNewest 丢弃当前日志
+	OverflowDropNewest
+	// OverflowDropOldest 丢弃队列中最早的日志，为当前日志腾出位置
+	OverflowDropOldest
+	// OverflowDropBelow 丢弃等级低于 Overflow.Level 的日志，等于或高于该等级的日志阻塞等待
+	OverflowDropBelow
+)
+
+// Overflow 异步日志队列满载时的处理配置
+type Overflow struct {
+	//处理策略
+	Policy OverflowPolicy
+	//OverflowBlockTimeout 策略的等待超时时间
+	Timeout time.Duration
+	//OverflowDropBelow 策略下始终保留的最低日志等级
+	Level contract.Level
+}
+
+// 记录被丢弃的日志
+func (r *Logger) drop(level contract.Level) {
+	if level < contract.LevelEmergency || level > contract.LevelDebug {
+		level = contract.LevelDebug
+	}
+	atomic.AddUint64(&r.dropped[level], 1)
+}
+
+// Dropped 返回异步日志队列满载时被丢弃的日志总数，同一条日志在多个日志处理器的队列中被丢弃时分别计数
+func (r *Logger) Dropped() uint64 {
+	var n uint64
+	for i := range r.root.dropped {
+		n += atomic.LoadUint64(&r.root.dropped[i])
+	}
+	return n
+}
+
+// DroppedByLevel 返回异步日志队列满载时被丢弃的指定等级的日志数量
+func (r *Logger) DroppedByLevel(level contract.Level) uint64 {
+	if level < contract.LevelEmergency || level > contract.LevelDebug {
+		return 0
+	}
+	return atomic.LoadUint64(&r.root.dropped[level])
+}
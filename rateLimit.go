@@ -0,0 +1,229 @@
+package flog
+
+import (
+	"context"
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/internal/report"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 令牌桶
+type rateBucket struct {
+	lock *sync.Mutex
+	//每 every 补充一个令牌
+	every time.Duration
+	//令牌数量上限，即允许的突发数量
+	burst float64
+	//当前的令牌数量
+	tokens float64
+	//上次补充令牌的时间
+	last time.Time
+}
+
+func newRateBucket(every time.Duration, burst int) *rateBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	tmp := new(rateBucket)
+	tmp.lock = new(sync.Mutex)
+	tmp.every = every
+	tmp.burst = float64(burst)
+	tmp.tokens = tmp.burst
+	tmp.last = time.Now()
+	return tmp
+}
+
+// 补充令牌后取走一个令牌，没有令牌则返回false
+func (r *rateBucket) allow(now time.Time) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if elapsed := now.Sub(r.last); elapsed > 0 {
+		r.tokens += float64(elapsed) / float64(r.every)
+		if r.tokens > r.burst {
+			r.tokens = r.burst
+		}
+		r.last = now
+	}
+	if r.tokens < 1 {
+		return false
+	}
+	r.tokens--
+	return true
+}
+
+// 归还一个令牌
+func (r *rateBucket) refund() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.tokens++; r.tokens > r.burst {
+		r.tokens = r.burst
+	}
+}
+
+// 不限流的占位令牌桶
+var unlimitedBucket = new(rateBucket)
+
+// RateLimitHandler 对日志进行令牌桶限流的日志处理器包装器，用于保护钉钉、http等远程日志处理器
+// 日志需要同时拿到日志等级与日志通道的令牌才交给被包装的日志处理器，没有单独设置的日志等级使用默认的令牌桶
+// 超出限制的日志交给后备日志处理器，没有后备日志处理器则丢弃，限流规则需要在使用之前设置
+type RateLimitHandler struct {
+	//被限流的数量，放在结构体开头，保证32位平台上原子操作的内存对齐
+	limited uint64
+	handler contract.Handler
+	//超出限制的日志的后备日志处理器
+	fallback contract.Handler
+	//默认的令牌桶，nil 表示不限流
+	bucket *rateBucket
+	//各个日志等级单独设置的令牌桶
+	levels [contract.LevelDebug + 1]*rateBucket
+	//各个日志通道单独设置的令牌桶
+	channels map[string]*rateBucket
+}
+
+// NewRateLimitHandler 包装日志处理器，默认每 every 补充一个令牌，最多允许 burst 条日志的突发，every 小于等于0表示默认不限流
+// 比如钉钉机器人每分钟最多20条消息：NewRateLimitHandler(handler, 3*time.Second, 20)
+func NewRateLimitHandler(handler contract.Handler, every time.Duration, burst int) *RateLimitHandler {
+	tmp := new(RateLimitHandler)
+	tmp.handler = handler
+	if every > 0 {
+		tmp.bucket = newRateBucket(every, burst)
+	}
+	tmp.channels = map[string]*rateBucket{}
+	return tmp
+}
+
+// SetLevelLimit 单独设置某个日志等级的令牌桶，every 小于等于0表示该等级不限流
+func (r *RateLimitHandler) SetLevelLimit(level contract.Level, every time.Duration, burst int) *RateLimitHandler {
+	if level < contract.LevelEmergency || level > contract.LevelDebug {
+		return r
+	}
+	if every > 0 {
+		r.levels[level] = newRateBucket(every, burst)
+	} else {
+		//不限流的等级用一个永远有令牌的令牌桶占位，与没有设置区分开
+		r.levels[level] = unlimitedBucket
+	}
+	return r
+}
+
+// SetChannelLimit 单独设置某个日志通道的令牌桶，该通道的日志额外受此令牌桶限制，every 小于等于0表示取消设置
+func (r *RateLimitHandler) SetChannelLimit(channel string, every time.Duration, burst int) *RateLimitHandler {
+	if every > 0 {
+		r.channels[channel] = newRateBucket(every, burst)
+	} else {
+		delete(r.channels, channel)
+	}
+	return r
+}
+
+// SetFallback 设置超出限制的日志的后备日志处理器，后备日志处理器随包装器一起关闭
+func (r *RateLimitHandler) SetFallback(fallback contract.Handler) *RateLimitHandler {
+	r.fallback = fallback
+	return r
+}
+
+// Limited 返回超出限制的日志数量
+func (r *RateLimitHandler) Limited() uint64 {
+	return atomic.LoadUint64(&r.limited)
+}
+
+// 判断日志是否在限制之内
+func (r *RateLimitHandler) allow(record *contract.Record) bool {
+	now := time.Now()
+	bucket := r.bucket
+	if level := contract.GetLevelByName(record.Level); r.levels[level] != nil {
+		bucket = r.levels[level]
+	}
+	if bucket == unlimitedBucket {
+		bucket = nil
+	}
+	if bucket != nil && !bucket.allow(now) {
+		return false
+	}
+	if channel, ok := r.channels[record.Channel]; ok && !channel.allow(now) {
+		//日志通道没有令牌，归还已经取走的日志等级的令牌，避免被限流的日志消耗其它通道的额度
+		if bucket != nil {
+			bucket.refund()
+		}
+		return false
+	}
+	return true
+}
+
+func (r *RateLimitHandler) Handle(record *contract.Record) bool {
+	if r.allow(record) {
+		return r.handler.Handle(record)
+	}
+	atomic.AddUint64(&r.limited, 1)
+	if r.fallback != nil && r.fallback.IsHandling(contract.GetLevelByName(record.Level)) {
+		return r.fallback.Handle(record)
+	}
+	//被丢弃的日志继续交给下一个日志处理器
+	return false
+}
+
+func (r *RateLimitHandler) IsHandling(level contract.Level) bool {
+	return r.handler.IsHandling(level)
+}
+
+// Close 关闭被包装的日志处理器与后备日志处理器
+func (r *RateLimitHandler) Close() error {
+	err := r.handler.Close()
+	if r.fallback != nil {
+		if e := r.fallback.Close(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (r *RateLimitHandler) Flush(ctx context.Context) error {
+	var err error
+	if flusher, ok := r.handler.(contract.Flusher); ok {
+		err = flusher.Flush(ctx)
+	}
+	if flusher, ok := r.fallback.(contract.Flusher); ok {
+		if e := flusher.Flush(ctx); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// SetLevel 修改被包装的日志处理器的日志等级
+func (r *RateLimitHandler) SetLevel(level contract.Level) {
+	if leveler, ok := r.handler.(contract.Leveler); ok {
+		leveler.SetLevel(level)
+	}
+}
+
+func (r *RateLimitHandler) GetLevel() contract.Level {
+	if leveler, ok := r.handler.(contract.Leveler); ok {
+		return leveler.GetLevel()
+	}
+	return contract.LevelDebug
+}
+
+// SetErrorHandler 设置被包装的日志处理器与后备日志处理器的错误处理器
+func (r *RateLimitHandler) SetErrorHandler(handler contract.ErrorHandler) {
+	if setter, ok := r.handler.(contract.ErrorHandlerSetter); ok {
+		setter.SetErrorHandler(handler)
+	}
+	if setter, ok := r.fallback.(contract.ErrorHandlerSetter); ok {
+		setter.SetErrorHandler(handler)
+	}
+}
+
+func (r *RateLimitHandler) GetErrorHandler() contract.ErrorHandler {
+	if setter, ok := r.handler.(contract.ErrorHandlerSetter); ok {
+		return setter.GetErrorHandler()
+	}
+	return nil
+}
+
+// GetName 返回被包装的日志处理器的名称
+func (r *RateLimitHandler) GetName() string {
+	return report.Name(r.handler)
+}
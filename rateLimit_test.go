@@ -0,0 +1,78 @@
+package flog_test
+
+import (
+	"github.com/buexplain/go-flog"
+	"github.com/buexplain/go-flog/contract"
+	"testing"
+	"time"
+)
+
+func TestRateLimitHandler(t *testing.T) {
+	memory := newMemoryHandler(contract.LevelDebug)
+	fallback := newMemoryHandler(contract.LevelDebug)
+	//默认突发3条，错误日志不限流，payment 通道突发1条
+	limiter := flog.NewRateLimitHandler(memory, time.Hour, 3).
+		SetLevelLimit(contract.LevelError, 0, 0).
+		SetChannelLimit("payment", time.Hour, 1).
+		SetFallback(fallback)
+	logger := flog.New("rate", limiter)
+	for i := 0; i < 5; i++ {
+		logger.Info("info")
+		logger.Error("error")
+	}
+	payment := logger.WithChannel("payment")
+	payment.Error("payment")
+	payment.Error("payment")
+	if n := len(memory.getRecords()); n != 3+5+1 {
+		t.Errorf("期待通过 9 条日志，实际通过 %d 条", n)
+	}
+	records := fallback.getRecords()
+	if len(records) != 3 || records[0].Message != "info" || records[2].Message != "payment" || limiter.Limited() != 3 {
+		t.Error("超出限制的日志没有交给后备日志处理器", len(records), limiter.Limited())
+	}
+	if err := logger.Close(); err != nil {
+		t.Error("关闭日志收集器失败", err)
+	}
+	if memory.closed != 1 || fallback.closed != 1 {
+		t.Error("没有关闭被包装的日志处理器与后备日志处理器")
+	}
+}
+
+func TestRateLimitHandlerRefill(t *testing.T) {
+	memory := newMemoryHandler(contract.LevelDebug)
+	limiter := flog.NewRateLimitHandler(memory, 20*time.Millisecond, 1)
+	logger := flog.New("rate", limiter)
+	logger.Info("first")
+	logger.Info("limited")
+	time.Sleep(30 * time.Millisecond)
+	logger.Info("refilled")
+	records := memory.getRecords()
+	if len(records) != 2 || records[0].Message != "first" || records[1].Message != "refilled" {
+		t.Error("令牌桶补充令牌错误", len(records))
+	}
+	if limiter.Limited() != 1 {
+		t.Error("超出限制的日志数量错误", limiter.Limited())
+	}
+	_ = logger.Close()
+}
+
+func TestRateLimitHandlerRefund(t *testing.T) {
+	memory := newMemoryHandler(contract.LevelDebug)
+	//默认突发2条，payment 通道突发1条
+	limiter := flog.NewRateLimitHandler(memory, time.Hour, 2).SetChannelLimit("payment", time.Hour, 1)
+	logger := flog.New("rate", limiter)
+	payment := logger.WithChannel("payment")
+	payment.Info("payment")
+	//被通道限流的日志不应该消耗日志等级的令牌
+	payment.Info("limited")
+	payment.Info("limited")
+	logger.Info("info")
+	records := memory.getRecords()
+	if len(records) != 2 || records[0].Message != "payment" || records[1].Message != "info" {
+		t.Error("被日志通道限流的日志消耗了日志等级的令牌", len(records))
+	}
+	if limiter.Limited() != 2 {
+		t.Error("超出限制的日志数量错误", limiter.Limited())
+	}
+	_ = logger.Close()
+}
@@ -0,0 +1,327 @@
+package flog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/formatter"
+	"github.com/buexplain/go-flog/handler"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Template 按渠道名称创建日志收集器的模板
+type Template func(channel string) *Logger
+
+// 按渠道名称共享的日志收集器
+var registry = struct {
+	lock    *sync.RWMutex
+	loggers map[string]*Logger
+	//没有模板时由默认日志收集器创建的日志收集器，及创建时的默认日志收集器
+	derived map[string]*Logger
+	//正在创建的日志收集器，创建完毕后关闭
+	pending  map[string]chan struct{}
+	template Template
+}{lock: new(sync.RWMutex), loggers: map[string]*Logger{}, derived: map[string]*Logger{}, pending: map[string]chan struct{}{}}
+
+// 默认日志收集器，存放 *Logger
+var defaultLogger atomic.Value
+
+func init() {
+	defaultLogger.Store(New("default", handler.NewSTD(contract.LevelDebug, formatter.NewLine(), contract.LevelError)))
+}
+
+// Default 返回默认日志收集器，包级别的日志函数都写入默认日志收集器
+// 没有替换时默认日志收集器将日志输出到标准输出，错误及以上等级的日志输出到标准错误
+func Default() *Logger {
+	return defaultLogger.Load().(*Logger)
+}
+
+// SetDefault 替换默认日志收集器，被替换的日志收集器不会被关闭
+// 没有模板时由旧的默认日志收集器创建的共享日志收集器，在下次调用 Get 时按新的默认日志收集器重新创建
+// 已经通过 Get 拿到的日志收集器仍然写入旧的默认日志收集器
+func SetDefault(logger *Logger) {
+	if logger != nil {
+		defaultLogger.Store(logger)
+	}
+}
+
+// SetTemplate 设置创建共享日志收集器的模板，只影响之后创建的日志收集器
+// 没有设置模板时，共享日志收集器是默认日志收集器的子日志收集器
+func SetTemplate(template Template) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	registry.template = template
+}
+
+// Register 按渠道名称注册共享的日志收集器，同名的日志收集器会被替换但不会被关闭
+func Register(channel string, logger *Logger) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	delete(registry.derived, channel)
+	if logger == nil {
+		delete(registry.loggers, channel)
+		return
+	}
+	registry.loggers[channel] = logger
+}
+
+// Get 返回渠道名称对应的共享日志收集器，不存在则按模板创建
+// 同一个渠道名称只会创建一次，并发调用的go程等待创建完毕，模板内不能调用 Get 获取同一个渠道名称
+func Get(channel string) *Logger {
+	for {
+		registry.lock.Lock()
+		logger, ok := registry.loggers[channel]
+		if ok {
+			if parent, derived := registry.derived[channel]; !derived || parent == Default() {
+				registry.lock.Unlock()
+				return logger
+			}
+		}
+		if wait, ok := registry.pending[channel]; ok {
+			//其它go程正在创建，等待创建完毕后重新获取
+			registry.lock.Unlock()
+			<-wait
+			continue
+		}
+		done := make(chan struct{})
+		registry.pending[channel] = done
+		template := registry.template
+		registry.lock.Unlock()
+		return create(channel, template, logger, done)
+	}
+}
+
+// 在锁外创建共享的日志收集器，允许模板内调用 Get 获取其它渠道名称的日志收集器
+// stale 是需要重新创建的旧日志收集器，创建期间注册了同名的日志收集器时以注册的为准
+func create(channel string, template Template, stale *Logger, done chan struct{}) (logger *Logger) {
+	var parent *Logger
+	defer func() {
+		registry.lock.Lock()
+		defer registry.lock.Unlock()
+		delete(registry.pending, channel)
+		close(done)
+		if logger == nil {
+			//模板 panic
+			return
+		}
+		if exist, ok := registry.loggers[channel]; ok && exist != stale {
+			logger = exist
+			return
+		}
+		registry.loggers[channel] = logger
+		if parent != nil {
+			registry.derived[channel] = parent
+		} else {
+			delete(registry.derived, channel)
+		}
+	}()
+	if template != nil {
+		logger = template(channel)
+	}
+	if logger == nil {
+		parent = Default()
+		logger = parent.WithChannel(channel)
+	}
+	return logger
+}
+
+// CloseAll 关闭默认日志收集器与所有共享的日志收集器，并清空注册的日志收集器，用于程序退出前
+// 共享同一个根日志收集器的日志收集器只关闭一次
+func CloseAll(timeout ...time.Duration) error {
+	registry.lock.Lock()
+	loggers := registry.loggers
+	registry.loggers = map[string]*Logger{}
+	registry.derived = map[string]*Logger{}
+	registry.lock.Unlock()
+	roots := map[*Logger]struct{}{Default().root: {}}
+	for _, logger := range loggers {
+		roots[logger.root] = struct{}{}
+	}
+	//并行关闭，避免超时时间累加
+	bag := bytes.Buffer{}
+	lock := new(sync.Mutex)
+	wg := &sync.WaitGroup{}
+	for root := range roots {
+		wg.Add(1)
+		go func(root *Logger) {
+			defer wg.Done()
+			if e := root.Close(timeout...); e != nil {
+				lock.Lock()
+				bag.WriteString(e.Error())
+				bag.WriteByte('\n')
+				lock.Unlock()
+			}
+		}(root)
+	}
+	wg.Wait()
+	if bag.Len() > 0 {
+		return errors.New(bag.String())
+	}
+	return nil
+}
+
+// Log 写入默认日志收集器
+// 包级别的日志函数都直接调用 addRecord，保证调用栈深度与 Logger 的方法一致
+func Log(ctx context.Context, level contract.Level, message string, context ...interface{}) {
+	Default().addRecord(ctx, level, false, message, context, nil)
+}
+
+func LogF(ctx context.Context, level contract.Level, format string, v ...interface{}) {
+	Default().addRecord(ctx, level, true, format, v, nil)
+}
+
+func LogFields(ctx context.Context, level contract.Level, message string, fields ...contract.Field) {
+	Default().addRecord(ctx, level, false, message, nil, fields)
+}
+
+// Emergency 紧急情况：系统无法使用
+func Emergency(message string, context ...interface{}) {
+	Default().addRecord(nil, contract.LevelEmergency, false, message, context, nil)
+}
+
+func EmergencyF(format string, v ...interface{}) {
+	Default().addRecord(nil, contract.LevelEmergency, true, format, v, nil)
+}
+
+func EmergencyCtx(ctx context.Context, message string, context ...interface{}) {
+	Default().addRecord(ctx, contract.LevelEmergency, false, message, context, nil)
+}
+
+// Alert 警报：必须立即采取措施
+func Alert(message string, context ...interface{}) {
+	Default().addRecord(nil, contract.LevelAlert, false, message, context, nil)
+}
+
+func AlertF(format string, v ...interface{}) {
+	Default().addRecord(nil, contract.LevelAlert, true, format, v, nil)
+}
+
+func AlertCtx(ctx context.Context, message string, context ...interface{}) {
+	Default().addRecord(ctx, contract.LevelAlert, false, message, context, nil)
+}
+
+// Critical 严重：危急情况
+func Critical(message string, context ...interface{}) {
+	Default().addRecord(nil, contract.LevelCritical, false, message, context, nil)
+}
+
+func CriticalF(format string, v ...interface{}) {
+	Default().addRecord(nil, contract.LevelCritical, true, format, v, nil)
+}
+
+func CriticalCtx(ctx context.Context, message string, context ...interface{}) {
+	Default().addRecord(ctx, contract.LevelCritical, false, message, context, nil)
+}
+
+func Error(message string, context ...interface{}) {
+	Default().addRecord(nil, contract.LevelError, false, message, context, nil)
+}
+
+func ErrorF(format string, v ...interface{}) {
+	Default().addRecord(nil, contract.LevelError, true, format, v, nil)
+}
+
+func ErrorCtx(ctx context.Context, message string, context ...interface{}) {
+	Default().addRecord(ctx, contract.LevelError, false, message, context, nil)
+}
+
+// Warning 警告
+func Warning(message string, context ...interface{}) {
+	Default().addRecord(nil, contract.LevelWarning, false, message, context, nil)
+}
+
+func WarningF(format string, v ...interface{}) {
+	Default().addRecord(nil, contract.LevelWarning, true, format, v, nil)
+}
+
+func WarningCtx(ctx context.Context, message string, context ...interface{}) {
+	Default().addRecord(ctx, contract.LevelWarning, false, message, context, nil)
+}
+
+// Notice 注意：正常但重要条件
+func Notice(message string, context ...interface{}) {
+	Default().addRecord(nil, contract.LevelNotice, false, message, context, nil)
+}
+
+func NoticeF(format string, v ...interface{}) {
+	Default().addRecord(nil, contract.LevelNotice, true, format, v, nil)
+}
+
+func NoticeCtx(ctx context.Context, message string, context ...interface{}) {
+	Default().addRecord(ctx, contract.LevelNotice, false, message, context, nil)
+}
+
+// Info 信息
+func Info(message string, context ...interface{}) {
+	Default().addRecord(nil, contract.LevelInfo, false, message, context, nil)
+}
+
+func InfoF(format string, v ...interface{}) {
+	Default().addRecord(nil, contract.LevelInfo, true, format, v, nil)
+}
+
+func InfoCtx(ctx context.Context, message string, context ...interface{}) {
+	Default().addRecord(ctx, contract.LevelInfo, false, message, context, nil)
+}
+
+// Debug 调试
+func Debug(message string, context ...interface{}) {
+	Default().addRecord(nil, contract.LevelDebug, false, message, context, nil)
+}
+
+func DebugF(format string, v ...interface{}) {
+	Default().addRecord(nil, contract.LevelDebug, true, format, v, nil)
+}
+
+func DebugCtx(ctx context.Context, message string, context ...interface{}) {
+	Default().addRecord(ctx, contract.LevelDebug, false, message, context, nil)
+}
+
+// Fatal 写入默认日志收集器，冲刷所有日志处理器后调用 os.Exit(1) 退出进程
+func Fatal(message string, context ...interface{}) {
+	logger := Default()
+	logger.addRecord(nil, contract.LevelEmergency, false, message, context, nil)
+	logger.flushBeforeExit()
+	os.Exit(1)
+}
+
+func FatalF(format string, v ...interface{}) {
+	logger := Default()
+	logger.addRecord(nil, contract.LevelEmergency, true, format, v, nil)
+	logger.flushBeforeExit()
+	os.Exit(1)
+}
+
+// Panic 写入默认日志收集器，冲刷所有日志处理器后以日志信息 panic
+func Panic(message string, context ...interface{}) {
+	logger := Default()
+	logger.addRecord(nil, contract.LevelCritical, false, message, context, nil)
+	logger.flushBeforeExit()
+	panic(message)
+}
+
+func PanicF(format string, v ...interface{}) {
+	logger := Default()
+	logger.addRecord(nil, contract.LevelCritical, true, format, v, nil)
+	logger.flushBeforeExit()
+	panic(fmt.Sprintf(format, resolveArgs(v)...))
+}
+
+// Recover 用于 defer，捕获 panic 后写入默认日志收集器，用法：defer flog.Recover(false)
+func Recover(repanic bool) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	logger := Default()
+	logger.recovered().addRecord(nil, contract.LevelCritical, false, fmt.Sprintf("panic: %v", v), []interface{}{v}, nil)
+	logger.flushBeforeExit()
+	if repanic {
+		panic(v)
+	}
+}
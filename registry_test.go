@@ -0,0 +1,107 @@
+package flog_test
+
+import (
+	"github.com/buexplain/go-flog"
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/extra"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	old := flog.Default()
+	defer flog.SetDefault(old)
+	defer flog.SetTemplate(nil)
+	memory := newMemoryHandler(contract.LevelDebug)
+	flog.SetDefault(flog.New("default", memory, extra.NewFuncCaller()))
+	//包级别的日志函数写入默认日志收集器
+	flog.Info("info")
+	flog.ErrorF("error %d", 1)
+	//没有设置模板时，共享日志收集器是默认日志收集器的子日志收集器
+	payment := flog.Get("payment")
+	if payment != flog.Get("payment") || payment.GetChannel() != "payment" {
+		t.Error("共享日志收集器错误")
+	}
+	payment.Warning("payment")
+	records := memory.getRecords()
+	if len(records) != 3 || records[0].Message != "info" || records[1].Message != "error 1" || records[1].Level != "error" || records[2].Channel != "payment" {
+		t.Error("包级别的日志函数写入错误", len(records))
+		return
+	}
+	if file, ok := records[0].Extra["File"].(string); !ok || !strings.HasSuffix(file, "registry_test.go") {
+		t.Error("包级别的日志函数获取日志调用者失败", records[0].Extra)
+	}
+	//按模板创建共享日志收集器
+	order := newMemoryHandler(contract.LevelDebug)
+	flog.SetTemplate(func(channel string) *flog.Logger {
+		return flog.New(channel, order)
+	})
+	flog.Get("order").Info("order")
+	custom := newMemoryHandler(contract.LevelDebug)
+	flog.Register("custom", flog.New("custom", custom))
+	flog.Get("custom").Info("custom")
+	if len(order.getRecords()) != 1 || order.getRecords()[0].Channel != "order" || len(custom.getRecords()) != 1 {
+		t.Error("按模板创建或者注册共享日志收集器错误")
+	}
+	//关闭所有日志收集器，共享根日志收集器的只关闭一次
+	if err := flog.CloseAll(); err != nil {
+		t.Error("关闭所有日志收集器失败", err)
+	}
+	if memory.closed != 1 || order.closed != 1 || custom.closed != 1 {
+		t.Error("关闭所有日志收集器错误", memory.closed, order.closed, custom.closed)
+	}
+	if flog.Get("payment") == payment {
+		t.Error("关闭所有日志收集器后应该清空注册的日志收集器")
+	}
+}
+
+func TestRegistrySingleFlight(t *testing.T) {
+	defer flog.SetTemplate(nil)
+	var created int32
+	memory := newMemoryHandler(contract.LevelDebug)
+	flog.SetTemplate(func(channel string) *flog.Logger {
+		atomic.AddInt32(&created, 1)
+		return flog.New(channel, memory)
+	})
+	wg := &sync.WaitGroup{}
+	loggers := make([]*flog.Logger, 10)
+	for i := 0; i < len(loggers); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			loggers[i] = flog.Get("single")
+		}(i)
+	}
+	wg.Wait()
+	for _, logger := range loggers {
+		if logger != loggers[0] {
+			t.Error("并发获取的共享日志收集器不一致")
+		}
+	}
+	if n := atomic.LoadInt32(&created); n != 1 {
+		t.Errorf("期待只创建 1 次，实际创建 %d 次", n)
+	}
+	if memory.closed != 0 {
+		t.Error("并发创建时不应该关闭日志收集器")
+	}
+	flog.Register("single", nil)
+	_ = loggers[0].Close()
+}
+
+func TestRegistryDefaultChanged(t *testing.T) {
+	old := flog.Default()
+	defer flog.SetDefault(old)
+	first := newMemoryHandler(contract.LevelDebug)
+	flog.SetDefault(flog.New("default", first))
+	flog.Get("stale").Info("first")
+	//替换默认日志收集器后，由旧的默认日志收集器创建的共享日志收集器重新创建
+	second := newMemoryHandler(contract.LevelDebug)
+	flog.SetDefault(flog.New("default", second))
+	flog.Get("stale").Info("second")
+	if len(first.getRecords()) != 1 || len(second.getRecords()) != 1 || second.getRecords()[0].Channel != "stale" {
+		t.Error("替换默认日志收集器后，共享日志收集器仍然写入旧的默认日志收集器", len(first.getRecords()), len(second.getRecords()))
+	}
+	flog.Register("stale", nil)
+}
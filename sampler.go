@@ -0,0 +1,341 @@
+package flog
+
+import (
+	"context"
+	"fmt"
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/internal/report"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 每个日志等级的计数器数量，信息按哈希值分配计数器，哈希冲突的信息共享计数器
+const samplerCounters = 4096
+
+// 采样计数器
+type samplerCounter struct {
+	//计数重置时间
+	resetAt int64
+	//时间窗口内的计数
+	count uint64
+}
+
+// 计数加一，超过重置时间则从1开始重新计数
+func (r *samplerCounter) inc(now int64, tick time.Duration) uint64 {
+	resetAt := atomic.LoadInt64(&r.resetAt)
+	if resetAt > now {
+		return atomic.AddUint64(&r.count, 1)
+	}
+	atomic.StoreUint64(&r.count, 1)
+	if !atomic.CompareAndSwapInt64(&r.resetAt, resetAt, now+int64(tick)) {
+		//其它go程已经重置计数
+		return atomic.AddUint64(&r.count, 1)
+	}
+	return 1
+}
+
+// 单个日志等级的采样规则
+type samplerRule struct {
+	//是否采样
+	enabled bool
+	//时间窗口内全部通过的日志数量
+	first uint64
+	//超过 first 后每 thereafter 条通过一条，0表示全部丢弃
+	thereafter uint64
+}
+
+// Sampler 日志采样器，用于抑制短时间内大量重复的日志
+// 每个时间窗口内，同一条信息的前 first 条日志全部通过，之后每 thereafter 条通过一条，其余日志被丢弃
+// 被丢弃的日志数量会按日志等级定期汇总为一条日志
+// 一个采样器只能用于一个日志收集器或者一个日志处理器，采样规则需要在使用之前设置
+type Sampler struct {
+	//各个等级尚未汇总的被丢弃的日志数量，放在结构体开头，保证32位平台上原子操作的内存对齐
+	sampled [contract.LevelDebug + 1]uint64
+	//时间窗口
+	tick time.Duration
+	//汇总被丢弃的日志数量的时间间隔
+	interval time.Duration
+	//各个等级的采样规则
+	rules [contract.LevelDebug + 1]samplerRule
+	//各个等级的计数器
+	counters [contract.LevelDebug + 1]*[samplerCounters]samplerCounter
+	//按格式字符串采样格式化日志，避免被丢弃的日志也要格式化
+	byFormat bool
+	//保护汇总go程的开启与关闭
+	lock *sync.Mutex
+	//汇总go程是否在运行
+	running bool
+	//汇总go程的关闭信号
+	closed chan struct{}
+	//汇总go程的退出信号
+	done chan struct{}
+}
+
+// NewSampler 创建所有日志等级采用相同规则的采样器
+func NewSampler(tick time.Duration, first, thereafter int) *Sampler {
+	tmp := new(Sampler)
+	tmp.tick = tick
+	tmp.interval = 10 * time.Second
+	for level := contract.LevelEmergency; level <= contract.LevelDebug; level++ {
+		tmp.SetLevel(level, first, thereafter)
+	}
+	tmp.lock = new(sync.Mutex)
+	return tmp
+}
+
+// SetLevel 单独设置某个日志等级的采样规则，first 小于0表示该等级不采样
+func (r *Sampler) SetLevel(level contract.Level, first, thereafter int) *Sampler {
+	if level < contract.LevelEmergency || level > contract.LevelDebug {
+		return r
+	}
+	if first < 0 {
+		r.rules[level] = samplerRule{}
+		r.counters[level] = nil
+		return r
+	}
+	if thereafter < 0 {
+		thereafter = 0
+	}
+	r.rules[level] = samplerRule{enabled: true, first: uint64(first), thereafter: uint64(thereafter)}
+	if r.counters[level] == nil {
+		r.counters[level] = new([samplerCounters]samplerCounter)
+	}
+	return r
+}
+
+// SetInterval 设置汇总被丢弃的日志数量的时间间隔
+func (r *Sampler) SetInterval(interval time.Duration) *Sampler {
+	if interval > 0 {
+		r.interval = interval
+	}
+	return r
+}
+
+// SetSampleByFormat 设置格式化日志是否按格式字符串采样，默认按格式化后的信息采样
+// 按格式字符串采样时，被丢弃的日志无需格式化，但是同一个格式字符串的不同信息会被一起采样
+func (r *Sampler) SetSampleByFormat(byFormat bool) *Sampler {
+	r.byFormat = byFormat
+	return r
+}
+
+// Sample 判断日志是否通过采样
+func (r *Sampler) Sample(level contract.Level, message string) bool {
+	if level < contract.LevelEmergency || level > contract.LevelDebug {
+		return true
+	}
+	rule := r.rules[level]
+	if !rule.enabled {
+		return true
+	}
+	n := r.counters[level][fnv32a(message)%samplerCounters].inc(time.Now().UnixNano(), r.tick)
+	if n <= rule.first || (rule.thereafter > 0 && (n-rule.first)%rule.thereafter == 0) {
+		return true
+	}
+	atomic.AddUint64(&r.sampled[level], 1)
+	return false
+}
+
+// Sampled 返回某个日志等级尚未汇总的被丢弃的日志数量
+func (r *Sampler) Sampled(level contract.Level) uint64 {
+	if level < contract.LevelEmergency || level > contract.LevelDebug {
+		return 0
+	}
+	return atomic.LoadUint64(&r.sampled[level])
+}
+
+// 开启汇总go程，定期将各个等级被丢弃的日志数量交给 fn，汇总go程已经在运行时不做处理
+// 关闭后可以再次开启，采样器可以重新设置给日志收集器
+func (r *Sampler) start(fn func(level contract.Level, n uint64)) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.running {
+		return
+	}
+	r.running = true
+	r.closed = make(chan struct{})
+	r.done = make(chan struct{})
+	go r.run(fn, r.closed, r.done)
+}
+
+func (r *Sampler) run(fn func(level contract.Level, n uint64), closed chan struct{}, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.summary(fn)
+		case <-closed:
+			r.summary(fn)
+			return
+		}
+	}
+}
+
+// 关闭汇总go程，关闭前汇总一次，汇总go程没有运行时不做处理
+func (r *Sampler) stop() {
+	r.lock.Lock()
+	if !r.running {
+		r.lock.Unlock()
+		return
+	}
+	r.running = false
+	close(r.closed)
+	done := r.done
+	r.lock.Unlock()
+	<-done
+}
+
+func (r *Sampler) summary(fn func(level contract.Level, n uint64)) {
+	for level := contract.LevelEmergency; level <= contract.LevelDebug; level++ {
+		if n := atomic.SwapUint64(&r.sampled[level], 0); n > 0 {
+			fn(level, n)
+		}
+	}
+}
+
+// 汇总日志的信息
+func sampledMessage(level contract.Level, n uint64) string {
+	return fmt.Sprintf("%d %s records sampled away", n, contract.GetNameByLevel(level))
+}
+
+// fnv32a 哈希，不分配内存
+func fnv32a(s string) uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+	hash := uint32(offset32)
+	for i := 0; i < len(s); i++ {
+		hash ^= uint32(s[i])
+		hash *= prime32
+	}
+	return hash
+}
+
+// SetSampler 设置日志收集器的采样器，按信息采样，nil 表示取消采样
+// 被丢弃的日志数量定期以同等级的日志汇总，替换或者关闭日志收集器时会汇总一次
+func (r *Logger) SetSampler(sampler *Sampler) {
+	root := r.root
+	var old *Sampler
+	root.update(func(s *snapshot) {
+		old = s.sampler
+		s.sampler = sampler
+	})
+	if old == sampler {
+		return
+	}
+	if old != nil {
+		old.stop()
+	}
+	if sampler != nil {
+		sampler.start(root.sampled)
+	}
+}
+
+// 写入被采样丢弃的日志数量的汇总日志，汇总日志不参与采样
+func (r *Logger) sampled(level contract.Level, n uint64) {
+	s := r.acquire()
+	if s == nil {
+		return
+	}
+	defer s.release()
+	if !s.isHandling(level) {
+		return
+	}
+	record := r.newRecord(level)
+	defer record.Release()
+	record.Message = sampledMessage(level, n)
+	record.Extra["Sampled"] = n
+	if !s.filter(record) {
+		return
+	}
+	r.emit(s, record, level)
+}
+
+// SamplingHandler 对日志进行采样的日志处理器包装器，只对被包装的日志处理器生效
+type SamplingHandler struct {
+	handler contract.Handler
+	sampler *Sampler
+}
+
+// NewSamplingHandler 包装日志处理器，被丢弃的日志数量定期以同等级的日志写入被包装的日志处理器
+func NewSamplingHandler(handler contract.Handler, sampler *Sampler) *SamplingHandler {
+	tmp := new(SamplingHandler)
+	tmp.handler = handler
+	tmp.sampler = sampler
+	sampler.start(tmp.sampled)
+	return tmp
+}
+
+// 写入被采样丢弃的日志数量的汇总日志
+func (r *SamplingHandler) sampled(level contract.Level, n uint64) {
+	if !r.handler.IsHandling(level) {
+		return
+	}
+	record := contract.AcquireRecord()
+	defer record.Release()
+	record.Level = contract.GetNameByLevel(level)
+	record.Message = sampledMessage(level, n)
+	record.Extra["Sampled"] = n
+	r.handler.Handle(record)
+}
+
+func (r *SamplingHandler) Handle(record *contract.Record) bool {
+	if !r.sampler.Sample(contract.GetLevelByName(record.Level), record.Message) {
+		//被丢弃的日志继续交给下一个日志处理器
+		return false
+	}
+	return r.handler.Handle(record)
+}
+
+func (r *SamplingHandler) IsHandling(level contract.Level) bool {
+	return r.handler.IsHandling(level)
+}
+
+// Close 汇总一次被丢弃的日志数量后关闭被包装的日志处理器
+func (r *SamplingHandler) Close() error {
+	r.sampler.stop()
+	return r.handler.Close()
+}
+
+func (r *SamplingHandler) Flush(ctx context.Context) error {
+	if flusher, ok := r.handler.(contract.Flusher); ok {
+		return flusher.Flush(ctx)
+	}
+	return nil
+}
+
+// SetLevel 修改被包装的日志处理器的日志等级
+func (r *SamplingHandler) SetLevel(level contract.Level) {
+	if leveler, ok := r.handler.(contract.Leveler); ok {
+		leveler.SetLevel(level)
+	}
+}
+
+func (r *SamplingHandler) GetLevel() contract.Level {
+	if leveler, ok := r.handler.(contract.Leveler); ok {
+		return leveler.GetLevel()
+	}
+	return contract.LevelDebug
+}
+
+// SetErrorHandler 设置被包装的日志处理器的错误处理器
+func (r *SamplingHandler) SetErrorHandler(handler contract.ErrorHandler) {
+	if setter, ok := r.handler.(contract.ErrorHandlerSetter); ok {
+		setter.SetErrorHandler(handler)
+	}
+}
+
+func (r *SamplingHandler) GetErrorHandler() contract.ErrorHandler {
+	if setter, ok := r.handler.(contract.ErrorHandlerSetter); ok {
+		return setter.GetErrorHandler()
+	}
+	return nil
+}
+
+// GetName 返回被包装的日志处理器的名称
+func (r *SamplingHandler) GetName() string {
+	return report.Name(r.handler)
+}
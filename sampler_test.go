@@ -0,0 +1,117 @@
+package flog_test
+
+import (
+	"github.com/buexplain/go-flog"
+	"github.com/buexplain/go-flog/contract"
+	"testing"
+	"time"
+)
+
+func TestSampler(t *testing.T) {
+	for i := 0; i < 4; i++ {
+		async, byFormat := i%2 == 1, i >= 2
+		memory := newMemoryHandler(contract.LevelDebug)
+		logger := flog.New("sampler", memory)
+		if async {
+			logger.Async(100)
+		}
+		//每条信息前2条通过，之后每3条通过一条，错误日志不采样
+		sampler := flog.NewSampler(time.Minute, 2, 3).SetLevel(contract.LevelError, -1, 0).SetSampleByFormat(byFormat)
+		logger.SetSampler(sampler)
+		for i := 0; i < 10; i++ {
+			logger.Info("repeat")
+			logger.InfoF("format %d", i)
+			logger.Error("error")
+		}
+		logger.Debug("once")
+		//默认按格式化后的信息采样，各条格式化日志互不影响
+		dropped := uint64(6)
+		if byFormat {
+			dropped = 12
+		}
+		if n := sampler.Sampled(contract.LevelInfo); n != dropped {
+			t.Errorf("期待丢弃 %d 条 info 日志，实际丢弃 %d 条", dropped, n)
+		}
+		if err := logger.Close(10 * time.Millisecond); err != nil {
+			t.Error("关闭日志收集器失败", err)
+		}
+		count := map[string]int{}
+		var summary *contract.Record
+		for _, record := range memory.getRecords() {
+			if _, ok := record.Extra["Sampled"]; ok {
+				summary = record
+				continue
+			}
+			count[record.Message]++
+		}
+		//10条日志通过 1、2、5、8
+		if count["repeat"] != 4 || count["format 0"] != 1 || count["format 1"] != 1 || count["format 4"] != 1 || count["format 7"] != 1 || count["error"] != 10 || count["once"] != 1 {
+			t.Error("采样结果错误", async, byFormat, count)
+		}
+		if !byFormat && count["format 2"] != 1 {
+			t.Error("按格式化后的信息采样时，不同的信息不应该被一起采样", count)
+		}
+		if summary == nil || summary.Level != "info" || summary.Extra["Sampled"] != dropped {
+			t.Error("被丢弃的日志数量汇总错误", async, byFormat, summary)
+		}
+	}
+}
+
+func TestSamplerWindow(t *testing.T) {
+	memory := newMemoryHandler(contract.LevelDebug)
+	logger := flog.New("sampler", memory)
+	sampler := flog.NewSampler(50*time.Millisecond, 1, 0).SetInterval(10 * time.Millisecond)
+	logger.SetSampler(sampler)
+	logger.Info("window")
+	logger.Info("window")
+	time.Sleep(100 * time.Millisecond)
+	//新的时间窗口重新计数，汇总日志已经定期写入
+	logger.Info("window")
+	records := memory.getRecords()
+	if len(records) != 3 || records[1].Extra["Sampled"] != uint64(1) || records[2].Message != "window" {
+		t.Error("时间窗口或者定期汇总错误", records)
+	}
+	//取消采样
+	logger.SetSampler(nil)
+	logger.Info("window")
+	if len(memory.getRecords()) != 4 {
+		t.Error("取消采样失败")
+	}
+	//关闭后的采样器可以重新设置
+	logger.SetSampler(sampler)
+	logger.Info("again")
+	logger.Info("again")
+	logger.SetSampler(nil)
+	logger.SetSampler(sampler)
+	if err := logger.Close(); err != nil {
+		t.Error(err)
+	}
+	if got := messages(memory.getRecords()); len(got) != 6 || got[4] != "again" || memory.getRecords()[5].Extra["Sampled"] != uint64(1) {
+		t.Error("重新设置采样器后采样错误", got)
+	}
+}
+
+func TestSamplingHandler(t *testing.T) {
+	memory := newMemoryHandler(contract.LevelDebug)
+	sampled := flog.NewSamplingHandler(memory, flog.NewSampler(time.Minute, 1, 0))
+	backup := newMemoryHandler(contract.LevelDebug)
+	logger := flog.New("sampler", sampled)
+	logger.PushHandler(backup)
+	for i := 0; i < 5; i++ {
+		logger.Warning("repeat")
+	}
+	if err := logger.Close(); err != nil {
+		t.Error("关闭日志收集器失败", err)
+	}
+	records := memory.getRecords()
+	if len(records) != 2 || records[0].Message != "repeat" || records[1].Extra["Sampled"] != uint64(4) || records[1].Level != "warning" {
+		t.Error("采样日志处理器的结果错误", records)
+	}
+	if memory.closed != 1 {
+		t.Error("没有关闭被包装的日志处理器")
+	}
+	//被丢弃的日志继续交给下一个日志处理器
+	if len(backup.getRecords()) != 5 {
+		t.Error("被丢弃的日志应该交给下一个日志处理器", len(backup.getRecords()))
+	}
+}
@@ -0,0 +1,237 @@
+package flog
+
+import (
+	"context"
+	"github.com/buexplain/go-flog/contract"
+	"log/slog"
+	"runtime"
+)
+
+// SlogHandler 实现 slog.Handler，将 log/slog 的日志写入日志收集器
+// slog.Record 的属性写入日志的结构化字段，slog.With 添加的属性写入日志的附加信息，分组转换为嵌套的 map[string]interface{}
+type SlogHandler struct {
+	//日志收集器，With 添加的属性保存在日志收集器绑定的上下文信息中
+	logger *Logger
+	//当前所在的分组
+	groups []string
+}
+
+// NewSlogHandler 创建 slog.Handler，用法：slog.New(flog.NewSlogHandler(logger))
+func NewSlogHandler(logger *Logger) *SlogHandler {
+	tmp := new(SlogHandler)
+	tmp.logger = logger
+	return tmp
+}
+
+func (r *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return r.logger.load().isHandling(contract.FromSlogLevel(level))
+}
+
+// Handle 收集 slog 的日志，日志时间取自 slog.Record
+// FuncCaller 在 slog 的调用栈中无法得到正确的调用位置，所以改用 slog.Record 记录的调用位置
+func (r *SlogHandler) Handle(ctx context.Context, rec slog.Record) error {
+	root := r.logger.root
+	s := root.acquire()
+	if s == nil {
+		return nil
+	}
+	defer s.release()
+	level := contract.FromSlogLevel(rec.Level)
+	if !s.isHandling(level) {
+		return nil
+	}
+	if s.sampler != nil && !s.sampler.Sample(level, rec.Message) {
+		return nil
+	}
+	record := r.logger.newRecord(level)
+	defer record.Release()
+	record.Message = rec.Message
+	if !rec.Time.IsZero() {
+		record.Time = rec.Time
+	}
+	if rec.NumAttrs() > 0 {
+		if len(r.groups) == 0 {
+			rec.Attrs(func(attr slog.Attr) bool {
+				record.Fields = appendSlogField(record.Fields, attr)
+				return true
+			})
+		} else {
+			//分组内的属性合并为一个结构化字段
+			group := make(map[string]interface{}, rec.NumAttrs())
+			rec.Attrs(func(attr slog.Attr) bool {
+				setSlogAttr(group, attr)
+				return true
+			})
+			if len(group) > 0 {
+				record.Fields = append(record.Fields, Any(r.groups[0], nestSlogGroup(r.groups[1:], group)))
+			}
+		}
+	}
+	for _, v := range s.extras {
+		v.Processor(record)
+	}
+	if ctx != nil {
+		for _, v := range s.contextExtras {
+			v.ContextProcessor(ctx, record)
+		}
+	}
+	var caller *runtime.Frame
+	if rec.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{rec.PC}).Next()
+		caller = &frame
+	}
+	//跳过 Handle，之后 slog 包的栈帧由 captureStack 跳过
+	r.logger.process(s, record, level, caller, 1)
+	return nil
+}
+
+// WithAttrs 返回绑定了属性的 slog.Handler，属性写入日志的附加信息
+func (r *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return r
+	}
+	group := make(map[string]interface{}, len(attrs))
+	for _, attr := range attrs {
+		setSlogAttr(group, attr)
+	}
+	if len(group) == 0 {
+		return r
+	}
+	tmp := new(SlogHandler)
+	tmp.logger = r.logger.child(r.logger.channel)
+	tmp.groups = r.groups
+	if tmp.logger.fields == nil {
+		tmp.logger.fields = make(map[string]interface{}, len(group))
+	}
+	if len(r.groups) == 0 {
+		for k, v := range group {
+			tmp.logger.fields[k] = v
+		}
+		return tmp
+	}
+	//同名分组合并，父级的map可能被其它 slog.Handler 共享，合并时逐层复制
+	fields := tmp.logger.fields
+	for _, name := range r.groups[:len(r.groups)-1] {
+		sub := copySlogGroup(fields[name])
+		fields[name] = sub
+		fields = sub
+	}
+	last := r.groups[len(r.groups)-1]
+	sub := copySlogGroup(fields[last])
+	for k, v := range group {
+		sub[k] = v
+	}
+	fields[last] = sub
+	return tmp
+}
+
+// WithGroup 返回开启了分组的 slog.Handler，之后的属性都写入该分组
+func (r *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return r
+	}
+	tmp := new(SlogHandler)
+	tmp.logger = r.logger
+	tmp.groups = append(append(make([]string, 0, len(r.groups)+1), r.groups...), name)
+	return tmp
+}
+
+// 复制分组，不是分组的值会被覆盖
+func copySlogGroup(v interface{}) map[string]interface{} {
+	group, _ := v.(map[string]interface{})
+	tmp := make(map[string]interface{}, len(group)+1)
+	for k, v := range group {
+		tmp[k] = v
+	}
+	return tmp
+}
+
+// 按分组路径嵌套
+func nestSlogGroup(groups []string, group map[string]interface{}) map[string]interface{} {
+	for i := len(groups) - 1; i >= 0; i-- {
+		group = map[string]interface{}{groups[i]: group}
+	}
+	return group
+}
+
+// 将属性写入分组，遵循 slog 的约定：忽略空属性与空分组，键名为空的分组展开到上一级
+func setSlogAttr(group map[string]interface{}, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	if attr.Value.Kind() != slog.KindGroup {
+		group[attr.Key] = slogValue(attr.Value)
+		return
+	}
+	attrs := attr.Value.Group()
+	if len(attrs) == 0 {
+		return
+	}
+	if attr.Key == "" {
+		for _, v := range attrs {
+			setSlogAttr(group, v)
+		}
+		return
+	}
+	sub := make(map[string]interface{}, len(attrs))
+	for _, v := range attrs {
+		setSlogAttr(sub, v)
+	}
+	group[attr.Key] = sub
+}
+
+// 将 slog.Value 转换为普通的值
+func slogValue(value slog.Value) interface{} {
+	value = value.Resolve()
+	if value.Kind() != slog.KindGroup {
+		return value.Any()
+	}
+	group := make(map[string]interface{}, len(value.Group()))
+	for _, v := range value.Group() {
+		setSlogAttr(group, v)
+	}
+	return group
+}
+
+// 将属性按类型转换为结构化字段
+func appendSlogField(fields []contract.Field, attr slog.Attr) []contract.Field {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return fields
+	}
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		return append(fields, String(attr.Key, attr.Value.String()))
+	case slog.KindInt64:
+		return append(fields, Int64(attr.Key, attr.Value.Int64()))
+	case slog.KindUint64:
+		return append(fields, Uint64(attr.Key, attr.Value.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, Float64(attr.Key, attr.Value.Float64()))
+	case slog.KindBool:
+		return append(fields, Bool(attr.Key, attr.Value.Bool()))
+	case slog.KindDuration:
+		return append(fields, Duration(attr.Key, attr.Value.Duration()))
+	case slog.KindTime:
+		return append(fields, Time(attr.Key, attr.Value.Time()))
+	case slog.KindGroup:
+		attrs := attr.Value.Group()
+		if attr.Key == "" {
+			for _, v := range attrs {
+				fields = appendSlogField(fields, v)
+			}
+			return fields
+		}
+		group := make(map[string]interface{}, len(attrs))
+		for _, v := range attrs {
+			setSlogAttr(group, v)
+		}
+		if len(group) == 0 {
+			return fields
+		}
+		return append(fields, Any(attr.Key, group))
+	default:
+		return append(fields, Any(attr.Key, attr.Value.Any()))
+	}
+}
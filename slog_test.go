@@ -0,0 +1,95 @@
+package flog_test
+
+import (
+	"context"
+	"errors"
+	"github.com/buexplain/go-flog"
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/extra"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSlogHandler(t *testing.T) {
+	memory := newMemoryHandler(contract.LevelInfo)
+	logger := slog.New(flog.NewSlogHandler(flog.New("slog", memory, extra.NewFuncCaller())))
+	//等级映射
+	cases := []struct {
+		level slog.Level
+		name  string
+	}{
+		{slog.LevelInfo, "info"},
+		{contract.SlogLevelNotice, "notice"},
+		{slog.LevelWarn, "warning"},
+		{slog.LevelError, "error"},
+		{contract.SlogLevelCritical, "critical"},
+		{contract.SlogLevelAlert + 1, "alert"},
+		{contract.SlogLevelEmergency, "emergency"},
+	}
+	for _, c := range cases {
+		logger.Log(context.Background(), c.level, c.name)
+	}
+	if logger.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("日志处理器没有开启debug等级，slog 不应该开启debug等级")
+	}
+	logger.Debug("debug")
+	records := memory.getRecords()
+	if len(records) != len(cases) {
+		t.Fatalf("期待收集到 %d 条日志，实际收集到 %d 条", len(cases), len(records))
+	}
+	for i, c := range cases {
+		if records[i].Level != c.name || records[i].Message != c.name {
+			t.Errorf("slog.Level %d 期待转换为 %s，实际为 %s", c.level, c.name, records[i].Level)
+		}
+		if file, _ := records[i].Extra["File"].(string); !strings.HasSuffix(file, "slog_test.go") {
+			t.Error("没有使用 slog 记录的调用位置", file)
+		}
+	}
+	//属性与分组
+	memory = newMemoryHandler(contract.LevelDebug)
+	logger = slog.New(flog.NewSlogHandler(flog.New("slog", memory)))
+	logger.With("app", "flog").WithGroup("request").With("method", "GET").Info("message", slog.Int("status", 200), slog.Group("user", "name", "刘备"))
+	logger.Info("fields", "count", 3, slog.Group("", slog.Bool("inline", true)), slog.Group("empty"))
+	records = memory.getRecords()
+	if len(records) != 2 {
+		t.Fatalf("期待收集到 2 条日志，实际收集到 %d 条", len(records))
+	}
+	expectExtra := map[string]interface{}{"app": "flog", "request": map[string]interface{}{"method": "GET"}}
+	if !reflect.DeepEqual(records[0].Extra, expectExtra) {
+		t.Errorf("slog.With 的属性没有写入附加信息 %+v", records[0].Extra)
+	}
+	expectField := map[string]interface{}{"status": int64(200), "user": map[string]interface{}{"name": "刘备"}}
+	if len(records[0].Fields) != 1 || records[0].Fields[0].Key != "request" || !reflect.DeepEqual(records[0].Fields[0].Value(), expectField) {
+		t.Errorf("分组内的属性没有写入结构化字段 %+v", records[0].Fields)
+	}
+	fields := records[1].Fields
+	if len(fields) != 2 || fields[0].Key != "count" || fields[0].Type != contract.FieldTypeInt64 || fields[1].Key != "inline" || fields[1].Type != contract.FieldTypeBool {
+		t.Errorf("属性没有按类型转换为结构化字段 %+v", fields)
+	}
+}
+
+func TestSlogHandlerErrorStack(t *testing.T) {
+	memory := newMemoryHandler(contract.LevelDebug)
+	flogger := flog.New("slog", memory)
+	flogger.SetStackLevel(contract.LevelError)
+	logger := slog.New(flog.NewSlogHandler(flogger))
+	logger.Error("error", "err", errors.New("e1"))
+	logger.Info("info", "err", errors.New("e2"))
+	records := memory.getRecords()
+	if len(records) != 2 {
+		t.Fatalf("期待收集到 2 条日志，实际收集到 %d 条", len(records))
+	}
+	//slog 的日志与 flog 的日志遵循相同的捕获调用栈的日志等级
+	detail, ok := records[0].Fields[0].Interface.(*contract.ErrorDetail)
+	if !ok {
+		t.Fatalf("结构化字段中的错误没有转换为结构化的错误 %T", records[0].Fields[0].Interface)
+	}
+	if !strings.HasPrefix(detail.Stack, "github.com/buexplain/go-flog_test.TestSlogHandlerErrorStack\n") {
+		t.Error("调用栈应该从调用 slog 日志方法的位置开始", detail.Stack)
+	}
+	if detail, ok := records[1].Fields[0].Interface.(*contract.ErrorDetail); !ok || detail.Stack != "" {
+		t.Error("低于捕获调用栈的日志等级时不应该捕获调用栈", records[1].Fields)
+	}
+}
@@ -0,0 +1,403 @@
+package flog
+
+import (
+	"bytes"
+	"errors"
+	"github.com/buexplain/go-flog/contract"
+	"sync/atomic"
+)
+
+// 日志处理器与额外日志信息处理器集合的快照
+// 快照一经发布便不再修改，修改集合时复制一份新的快照并原子替换，日志收集过程读取快照无需加锁
+type snapshot struct {
+	//正在使用该快照收集日志的数量，放在结构体开头，保证32位平台上原子操作的内存对齐
+	users int64
+	//是否有一方在等待快照不再被占用
+	waiting int32
+	//占用全部释放的信号
+	idle chan struct{}
+	//日志处理器集合
+	handlers []contract.Handler
+	//是否为异步模式
+	async bool
+	//异步模式下各个日志处理器的写入go程，与日志处理器集合一一对应
+	workers []*worker
+	//额外日志信息处理器集合
+	extras []contract.Extra
+	//从 context.Context 提取额外日志信息的处理器集合
+	contextExtras []contract.ContextExtra
+	//采样器
+	sampler *Sampler
+	//日志过滤器集合，额外日志信息写入之后执行
+	filters []contract.Filter
+}
+
+func newSnapshot() *snapshot {
+	tmp := new(snapshot)
+	tmp.idle = make(chan struct{}, 1)
+	return tmp
+}
+
+// 复制快照，切片重新分配，修改新快照不会影响旧快照
+func (r *snapshot) clone() *snapshot {
+	tmp := newSnapshot()
+	tmp.handlers = append(make([]contract.Handler, 0, len(r.handlers)+1), r.handlers...)
+	tmp.async = r.async
+	if r.async {
+		tmp.workers = append(make([]*worker, 0, len(r.workers)+1), r.workers...)
+	}
+	tmp.extras = append(make([]contract.Extra, 0, len(r.extras)+1), r.extras...)
+	tmp.contextExtras = append(make([]contract.ContextExtra, 0, len(r.contextExtras)+1), r.contextExtras...)
+	tmp.sampler = r.sampler
+	tmp.filters = append(make([]contract.Filter, 0, len(r.filters)+1), r.filters...)
+	return tmp
+}
+
+// 判断是否有日志处理器可以处理该等级的日志
+func (r *snapshot) isHandling(level contract.Level) bool {
+	for _, v := range r.handlers {
+		if v.IsHandling(level) {
+			return true
+		}
+	}
+	return false
+}
+
+// 判断日志是否通过所有日志过滤器
+func (r *snapshot) filter(record *contract.Record) bool {
+	for _, v := range r.filters {
+		if !v.Filter(record) {
+			return false
+		}
+	}
+	return true
+}
+
+// 读取当前快照
+func (r *Logger) load() *snapshot {
+	return r.root.snapshot.Load().(*snapshot)
+}
+
+// 读取并占用当前快照，用完后必须调用 release 释放，日志收集器已经关闭时返回 nil
+// 占用后再次确认快照没有被替换、日志收集器没有关闭，保证替换快照或者关闭的一方在等待占用结束后，不会再有日志进入旧快照的日志处理器
+func (r *Logger) acquire() *snapshot {
+	root := r.root
+	for {
+		s := root.load()
+		atomic.AddInt64(&s.users, 1)
+		select {
+		case <-root.closed:
+			s.release()
+			return nil
+		default:
+		}
+		if root.load() == s {
+			return s
+		}
+		s.release()
+	}
+}
+
+// 释放快照，有一方在等待时，最后一个占用者发出信号
+func (r *snapshot) release() {
+	if atomic.AddInt64(&r.users, -1) == 0 && atomic.LoadInt32(&r.waiting) == 1 {
+		select {
+		case r.idle <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// 等待快照不再被占用，调用前必须保证不会再有新的占用，即快照已经被替换或者日志收集器已经关闭
+func (r *snapshot) wait() {
+	atomic.StoreInt32(&r.waiting, 1)
+	for atomic.LoadInt64(&r.users) > 0 {
+		<-r.idle
+	}
+	//传递信号，唤醒可能同时在等待的另一方
+	select {
+	case r.idle <- struct{}{}:
+	default:
+	}
+}
+
+// 复制当前快照，交给 fn 修改后原子替换
+func (r *Logger) update(fn func(s *snapshot)) {
+	root := r.root
+	root.lock.Lock()
+	defer root.lock.Unlock()
+	s := root.load().clone()
+	fn(s)
+	root.snapshot.Store(s)
+}
+
+// 复制当前快照，交给 fn 修改日志处理器集合以及其它集合后原子替换
+// 异步模式下，保留的日志处理器继续使用原来的写入go程，新增的日志处理器开启新的写入go程
+// 共用写入go程的异步模式下，新旧写入go程在旧队列清空之前并行处理日志，保留的日志处理器在切换期间可能收到乱序的日志
+// 被移除的日志处理器会在新快照发布后等待其队列中的日志处理完毕，但不会被关闭
+func (r *Logger) updateHandlers(fn func(s *snapshot)) (removed []contract.Handler) {
+	root := r.root
+	var stopped []*worker
+	root.lock.Lock()
+	old := root.load()
+	s := old.clone()
+	fn(s)
+	if s.async && root.chain {
+		//共用写入go程时，新的日志处理器集合使用新的写入go程，旧的写入go程处理完队列中的日志后退出
+		s.workers = []*worker{newWorker(root, newChainHandler(root, s.handlers), root.capacity)}
+		stopped = old.workers
+		removed = removedHandlers(old.handlers, s.handlers)
+	} else if s.async {
+		//按日志处理器匹配原来的写入go程
+		used := make([]bool, len(old.workers))
+		s.workers = make([]*worker, 0, len(s.handlers))
+		for _, handler := range s.handlers {
+			var w *worker
+			for i, v := range old.workers {
+				if !used[i] && v.handler == handler {
+					used[i] = true
+					w = v
+					break
+				}
+			}
+			if w == nil {
+				w = newWorker(root, handler, root.capacity)
+			}
+			s.workers = append(s.workers, w)
+		}
+		for i, v := range old.workers {
+			if !used[i] {
+				stopped = append(stopped, v)
+				removed = append(removed, v.handler)
+			}
+		}
+	} else {
+		removed = removedHandlers(old.handlers, s.handlers)
+	}
+	root.snapshot.Store(s)
+	root.lock.Unlock()
+	//等待仍在使用旧快照的日志收集完毕，此后被移除的日志处理器不会再收到新的日志
+	if len(removed) > 0 {
+		old.wait()
+	}
+	//并行等待被移除的日志处理器队列中的日志处理完毕
+	done := make(chan struct{}, len(stopped))
+	for _, w := range stopped {
+		go func(w *worker) {
+			w.stop(0)
+			done <- struct{}{}
+		}(w)
+	}
+	for range stopped {
+		<-done
+	}
+	return removed
+}
+
+func (r *Logger) PushHandler(handler contract.Handler) *Logger {
+	if handler != nil {
+		r.updateHandlers(func(s *snapshot) {
+			s.handlers = append(s.handlers, handler)
+		})
+	}
+	return r
+}
+
+// PopHandler 弹出最后一个日志处理器，异步模式下会等待该日志处理器队列中的日志处理完毕
+func (r *Logger) PopHandler() contract.Handler {
+	var tmp contract.Handler
+	r.updateHandlers(func(s *snapshot) {
+		if len(s.handlers) == 0 {
+			return
+		}
+		tmp = s.handlers[len(s.handlers)-1]
+		s.handlers = s.handlers[0 : len(s.handlers)-1]
+	})
+	return tmp
+}
+
+// InsertHandler 在指定位置插入日志处理器，index 超出范围时追加到末尾
+func (r *Logger) InsertHandler(index int, handler contract.Handler) *Logger {
+	if handler == nil {
+		return r
+	}
+	r.updateHandlers(func(s *snapshot) {
+		if index < 0 || index >= len(s.handlers) {
+			s.handlers = append(s.handlers, handler)
+			return
+		}
+		s.handlers = append(s.handlers, nil)
+		copy(s.handlers[index+1:], s.handlers[index:])
+		s.handlers[index] = handler
+	})
+	return r
+}
+
+// RemoveHandler 移除日志处理器，异步模式下会等待该日志处理器队列中的日志处理完毕，被移除的日志处理器需要调用方自行关闭
+func (r *Logger) RemoveHandler(handler contract.Handler) bool {
+	removed := r.updateHandlers(func(s *snapshot) {
+		for i, v := range s.handlers {
+			if v == handler {
+				s.handlers = append(s.handlers[0:i], s.handlers[i+1:]...)
+				return
+			}
+		}
+	})
+	return len(removed) > 0
+}
+
+// SetHandlers 原子替换全部日志处理器，返回被移除的日志处理器
+// 新的日志处理器集合生效后，才会等待被移除的日志处理器队列中的日志处理完毕，被移除的日志处理器需要调用方自行关闭
+func (r *Logger) SetHandlers(handlers ...contract.Handler) []contract.Handler {
+	return r.updateHandlers(func(s *snapshot) {
+		s.handlers = nonNilHandlers(handlers)
+	})
+}
+
+// ErrSwapClosed 日志收集器已经关闭时 Swap 返回的错误，此时新的日志处理器没有生效
+var ErrSwapClosed = errors.New("flog: swap on closed logger")
+
+// Swap 原子替换全部日志处理器与额外日志信息处理器，用于热加载配置，日志不会进入新旧混合的集合
+// 新的集合生效后，等待被移除的日志处理器队列中的日志处理完毕，然后关闭被移除的日志处理器，返回关闭时的错误
+// 日志收集器已经关闭时不做替换并返回 ErrSwapClosed，新的日志处理器由调用方自行关闭
+func (r *Logger) Swap(handlers []contract.Handler, extras []contract.Extra, contextExtras []contract.ContextExtra) error {
+	closed := false
+	removed := r.updateHandlers(func(s *snapshot) {
+		//持有关闭锁时判断，避免与 Close 并发
+		select {
+		case <-r.root.closed:
+			closed = true
+			return
+		default:
+		}
+		s.handlers = nonNilHandlers(handlers)
+		s.extras = append(make([]contract.Extra, 0, len(extras)), extras...)
+		s.contextExtras = append(make([]contract.ContextExtra, 0, len(contextExtras)), contextExtras...)
+	})
+	if closed {
+		return ErrSwapClosed
+	}
+	bag := bytes.Buffer{}
+	for _, v := range removed {
+		if e := v.Close(); e != nil {
+			bag.WriteString(e.Error())
+			bag.WriteByte('\n')
+		}
+	}
+	if bag.Len() > 0 {
+		return errors.New(bag.String())
+	}
+	return nil
+}
+
+// 返回在 old 中而不在 handlers 中的日志处理器
+func removedHandlers(old, handlers []contract.Handler) (removed []contract.Handler) {
+	used := make([]bool, len(handlers))
+	for _, handler := range old {
+		found := false
+		for i, v := range handlers {
+			if !used[i] && v == handler {
+				used[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			removed = append(removed, handler)
+		}
+	}
+	return removed
+}
+
+// 过滤掉 nil 日志处理器
+func nonNilHandlers(handlers []contract.Handler) []contract.Handler {
+	tmp := make([]contract.Handler, 0, len(handlers))
+	for _, v := range handlers {
+		if v != nil {
+			tmp = append(tmp, v)
+		}
+	}
+	return tmp
+}
+
+// GetHandlers 返回日志处理器集合的副本
+func (r *Logger) GetHandlers() []contract.Handler {
+	s := r.load()
+	return append(make([]contract.Handler, 0, len(s.handlers)), s.handlers...)
+}
+
+func (r *Logger) PushExtra(extra contract.Extra) *Logger {
+	r.update(func(s *snapshot) {
+		s.extras = append(s.extras, extra)
+	})
+	return r
+}
+
+func (r *Logger) PopExtra() contract.Extra {
+	var tmp contract.Extra
+	r.update(func(s *snapshot) {
+		if len(s.extras) == 0 {
+			return
+		}
+		tmp = s.extras[len(s.extras)-1]
+		s.extras = s.extras[0 : len(s.extras)-1]
+	})
+	return tmp
+}
+
+// GetExtras 返回额外日志信息处理器集合的副本
+func (r *Logger) GetExtras() []contract.Extra {
+	s := r.load()
+	return append(make([]contract.Extra, 0, len(s.extras)), s.extras...)
+}
+
+func (r *Logger) PushContextExtra(extra contract.ContextExtra) *Logger {
+	r.update(func(s *snapshot) {
+		s.contextExtras = append(s.contextExtras, extra)
+	})
+	return r
+}
+
+func (r *Logger) PopContextExtra() contract.ContextExtra {
+	var tmp contract.ContextExtra
+	r.update(func(s *snapshot) {
+		if len(s.contextExtras) == 0 {
+			return
+		}
+		tmp = s.contextExtras[len(s.contextExtras)-1]
+		s.contextExtras = s.contextExtras[0 : len(s.contextExtras)-1]
+	})
+	return tmp
+}
+
+// GetContextExtras 返回从 context.Context 提取额外日志信息的处理器集合的副本
+func (r *Logger) GetContextExtras() []contract.ContextExtra {
+	s := r.load()
+	return append(make([]contract.ContextExtra, 0, len(s.contextExtras)), s.contextExtras...)
+}
+
+// PushFilter 添加日志过滤器，被任意一个日志过滤器丢弃的日志不会进入日志处理器
+func (r *Logger) PushFilter(filter contract.Filter) *Logger {
+	r.update(func(s *snapshot) {
+		s.filters = append(s.filters, filter)
+	})
+	return r
+}
+
+func (r *Logger) PopFilter() contract.Filter {
+	var tmp contract.Filter
+	r.update(func(s *snapshot) {
+		if len(s.filters) == 0 {
+			return
+		}
+		tmp = s.filters[len(s.filters)-1]
+		s.filters = s.filters[0 : len(s.filters)-1]
+	})
+	return tmp
+}
+
+// GetFilters 返回日志过滤器集合的副本
+func (r *Logger) GetFilters() []contract.Filter {
+	s := r.load()
+	return append(make([]contract.Filter, 0, len(s.filters)), s.filters...)
+}
@@ -0,0 +1,89 @@
+package flog
+
+import (
+	"fmt"
+	"github.com/buexplain/go-flog/contract"
+	"log/slog"
+)
+
+// 延迟求值的最大嵌套层数
+const maxResolveDepth = 100
+
+// Valuer 函数形式的延迟求值的日志值，用于上下文、格式化参数、结构化字段与附加信息
+// 只有日志收集器确认有日志处理器处理该等级的日志时才会求值，并且在进入异步队列之前求值，结果反映调用时的状态
+// 用法：logger.Debug("dump", flog.Valuer(func() interface{} { return dump(db) }))
+type Valuer func() interface{}
+
+func (r Valuer) LogValue() interface{} {
+	return r()
+}
+
+// 判断是否是延迟求值的日志值，slog.LogValuer 也会被求值
+func isLazy(v interface{}) bool {
+	switch v.(type) {
+	case contract.LogValuer, slog.LogValuer:
+		return true
+	default:
+		return false
+	}
+}
+
+// 对延迟求值的日志值求值，求值恐慌时返回错误
+func resolve(v interface{}) (result interface{}) {
+	defer func() {
+		if re := recover(); re != nil {
+			result = fmt.Errorf("LogValue panicked: %v", re)
+		}
+	}()
+	for i := 0; i < maxResolveDepth; i++ {
+		switch x := v.(type) {
+		case contract.LogValuer:
+			v = x.LogValue()
+		case slog.LogValuer:
+			return slogValue(slog.AnyValue(x))
+		default:
+			return v
+		}
+	}
+	return fmt.Errorf("LogValue exceeded %d nested resolutions", maxResolveDepth)
+}
+
+// 对格式化参数中延迟求值的日志值求值，参数是调用方传入的切片，有需要求值的参数时复制后再修改
+func resolveArgs(args []interface{}) []interface{} {
+	var tmp []interface{}
+	for i, v := range args {
+		if isLazy(v) {
+			if tmp == nil {
+				tmp = append(make([]interface{}, 0, len(args)), args...)
+			}
+			tmp[i] = resolve(v)
+		}
+	}
+	if tmp == nil {
+		return args
+	}
+	return tmp
+}
+
+// 对日志的上下文、结构化字段与附加信息中延迟求值的日志值求值
+func resolveValues(record *contract.Record) {
+	switch context := record.Context.(type) {
+	case []interface{}:
+		//上下文可能是调用方传入的切片，复制后再修改
+		record.Context = resolveArgs(context)
+	default:
+		if isLazy(context) {
+			record.Context = resolve(context)
+		}
+	}
+	for i, field := range record.Fields {
+		if field.Type == contract.FieldTypeAny && isLazy(field.Interface) {
+			record.Fields[i] = Any(field.Key, resolve(field.Interface))
+		}
+	}
+	for k, v := range record.Extra {
+		if isLazy(v) {
+			record.Extra[k] = resolve(v)
+		}
+	}
+}
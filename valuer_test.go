@@ -0,0 +1,114 @@
+package flog_test
+
+import (
+	"github.com/buexplain/go-flog"
+	"github.com/buexplain/go-flog/contract"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 实现了 slog.LogValuer 的值
+type slogValuer struct{}
+
+func (r slogValuer) LogValue() slog.Value {
+	return slog.StringValue("slog")
+}
+
+func TestLoggerValuer(t *testing.T) {
+	memory := newMemoryHandler(contract.LevelInfo)
+	logger := flog.New("valuer", memory)
+	var called int32
+	lazy := flog.Valuer(func() interface{} {
+		atomic.AddInt32(&called, 1)
+		return "lazy"
+	})
+	//没有日志处理器处理调试日志，不会求值
+	logger.Debug("debug", lazy)
+	logger.With("bound", lazy).Debug("debug")
+	logger.LogFields(nil, contract.LevelDebug, "debug", flog.Any("field", lazy))
+	if atomic.LoadInt32(&called) != 0 {
+		t.Error("没有日志处理器处理的日志不应该对延迟求值的日志值求值")
+	}
+	context := []interface{}{lazy, 1}
+	logger.With("bound", lazy).Info("info", context...)
+	logger.LogFields(nil, contract.LevelInfo, "field", flog.Any("field", lazy))
+	logger.Info("slog", slogValuer{})
+	logger.Info("panic", flog.Valuer(func() interface{} {
+		panic("boom")
+	}))
+	records := memory.getRecords()
+	if len(records) != 4 {
+		t.Fatalf("期待收集到 4 条日志，实际收集到 %d 条", len(records))
+	}
+	if c, ok := records[0].Context.([]interface{}); !ok || c[0] != "lazy" || records[0].Extra["bound"] != "lazy" {
+		t.Error("上下文或者绑定的上下文信息没有求值", records[0].Context, records[0].Extra)
+	}
+	if _, ok := context[0].(flog.Valuer); !ok {
+		t.Error("求值不应该修改调用方传入的上下文")
+	}
+	if records[1].Fields[0].Value() != "lazy" {
+		t.Error("结构化字段没有求值", records[1].Fields[0])
+	}
+	if records[2].Context != "slog" {
+		t.Error("slog.LogValuer 没有求值", records[2].Context)
+	}
+	//求值恐慌转换为结构化的错误
+	if detail, ok := records[3].Context.(*contract.ErrorDetail); !ok || detail.Message != "LogValue panicked: boom" {
+		t.Error("求值恐慌没有转换为错误", records[3].Context)
+	}
+}
+
+func TestLoggerValuerAsync(t *testing.T) {
+	gate := newGateHandler()
+	logger := flog.New("valuer", gate)
+	logger.Async(10)
+	var state atomic.Value
+	state.Store("before")
+	logger.Info("block")
+	<-gate.entered
+	//写入go程被阻塞时收集的日志进入异步队列，之后修改状态，求值结果应该反映调用时的状态
+	logger.Info("async", flog.Valuer(func() interface{} {
+		return state.Load()
+	}))
+	state.Store("after")
+	close(gate.gate)
+	if err := logger.Close(10 * time.Millisecond); err != nil {
+		t.Error(err)
+	}
+	records := gate.getRecords()
+	if len(records) != 2 || records[1].Context != "before" {
+		t.Error("延迟求值的日志值应该在进入异步队列之前求值", records)
+	}
+}
+
+// 添加延迟求值的日志值的额外日志信息处理器
+type valuerExtra struct{}
+
+func (r valuerExtra) Processor(record *contract.Record) {
+	record.Extra["Lazy"] = flog.Valuer(func() interface{} {
+		return "extra"
+	})
+}
+
+func TestLoggerValuerFormatAndExtra(t *testing.T) {
+	memory := newMemoryHandler(contract.LevelDebug)
+	logger := flog.New("valuer", memory, valuerExtra{})
+	args := []interface{}{flog.Valuer(func() interface{} {
+		return 42
+	}), slogValuer{}}
+	//格式化参数在格式化之前求值
+	logger.DebugF("answer %v %v", args...)
+	records := memory.getRecords()
+	if len(records) != 1 || records[0].Message != "answer 42 slog" {
+		t.Fatal("格式化参数没有求值", records)
+	}
+	if _, ok := args[0].(flog.Valuer); !ok {
+		t.Error("不应该修改调用方传入的参数")
+	}
+	//额外日志信息处理器添加的延迟求值的日志值也会被求值
+	if records[0].Extra["Lazy"] != "extra" {
+		t.Error("额外日志信息处理器添加的日志值没有求值", records[0].Extra)
+	}
+}
@@ -0,0 +1,253 @@
+package flog
+
+import (
+	"context"
+	"fmt"
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/internal/metrics"
+	"github.com/buexplain/go-flog/internal/report"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+// 异步模式下每个日志处理器独占的写入go程与日志队列
+type worker struct {
+	//队列恢复空闲前累计被丢弃的日志数量，放在结构体开头，保证32位平台上原子操作的内存对齐
+	droppedPending uint64
+	//成功抛入队列的日志数量
+	enqueued uint64
+	//已经出队的日志数量，包括已经处理的与被丢弃的
+	dequeued uint64
+	//所属的日志收集器
+	logger *Logger
+	//日志处理器
+	handler contract.Handler
+	//日志队列
+	queue chan *contract.Record
+	//关闭信号
+	closed chan struct{}
+	//写入go程退出信号
+	done chan struct{}
+	//关闭时队列清空后继续等待迟到日志的时间
+	linger time.Duration
+}
+
+func newWorker(logger *Logger, handler contract.Handler, capacity int) *worker {
+	tmp := new(worker)
+	tmp.logger = logger
+	tmp.handler = handler
+	tmp.queue = make(chan *contract.Record, capacity)
+	tmp.closed = make(chan struct{})
+	tmp.done = make(chan struct{})
+	//开启日志异步写入go程
+	go tmp.goF()
+	return tmp
+}
+
+// 日志异步写入go程
+func (r *worker) goF() {
+	defer func() {
+		if a := recover(); a != nil {
+			//记录错误栈
+			err := fmt.Errorf("logger %s uncaught panic: %v\n%s", r.logger.channel, a, debug.Stack())
+			r.logger.handleError(&contract.Error{Handler: report.Name(r.handler), Kind: contract.ErrorKindPanic, Err: err})
+			//重启一条go程
+			go r.goF()
+		} else {
+			//正常退出，发出写入go程退出信号
+			close(r.done)
+		}
+	}()
+	for {
+		select {
+		case record := <-r.queue:
+			//处理日志
+			r.handle(record)
+			r.handleDropped()
+			break
+		case <-r.closed:
+			//收到关闭信号
+			//处理完日志队列中剩余的日志
+			//丢弃最早日志的策略下，生产者也会从队列中取出日志，所以不能阻塞读取
+			for len(r.queue) > 0 {
+				select {
+				case record := <-r.queue:
+					r.handle(record)
+				default:
+					break
+				}
+			}
+			//再次处理日志队列中剩余的日志，直到超时退出
+			for {
+				select {
+				case record := <-r.queue:
+					r.handle(record)
+				case <-time.After(r.linger):
+					//处理丢弃日志数量的汇总日志
+					r.handleDropped()
+					return
+				}
+			}
+		}
+	}
+}
+
+// 处理出队的日志，处理完毕后释放队列持有的引用
+func (r *worker) handle(record *contract.Record) {
+	defer atomic.AddUint64(&r.dequeued, 1)
+	defer record.Release()
+	r.call(record)
+}
+
+// 调用日志处理器
+func (r *worker) call(record *contract.Record) {
+	defer func() {
+		//捕获所有异常，即便日志崩溃，也不影响进程
+		err := recover()
+		if err != nil {
+			r.logger.handleError(&contract.Error{Handler: report.Name(r.handler), Kind: contract.ErrorKindPanic, Record: record, Err: report.Panic(err)})
+		}
+	}()
+	r.handler.Handle(record)
+}
+
+// 冲刷日志，等待调用前抛入队列的日志全部出队，再冲刷日志处理器的缓冲
+func (r *worker) flush(ctx context.Context) error {
+	target := atomic.LoadUint64(&r.enqueued)
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	for atomic.LoadUint64(&r.dequeued) < target {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-r.done:
+			//写入go程已经退出，队列中的日志不会再被处理
+			return nil
+		case <-ticker.C:
+			break
+		}
+	}
+	if flusher, ok := r.handler.(contract.Flusher); ok {
+		return flusher.Flush(ctx)
+	}
+	return nil
+}
+
+// 关闭写入go程，并等待日志队列处理完毕，linger 为队列清空后继续等待迟到日志的时间
+func (r *worker) stop(linger time.Duration) {
+	r.linger = linger
+	close(r.closed)
+	<-r.done
+	//写入go程退出后仍留在队列中的日志不会再被处理，释放队列持有的引用并计为被丢弃
+	for {
+		select {
+		case record := <-r.queue:
+			r.drop(record)
+		default:
+			return
+		}
+	}
+}
+
+// 将日志抛入日志队列，队列满载时按策略处理
+// 写入go程退出后不再阻塞等待，直接丢弃日志，避免生产者永久阻塞
+func (r *worker) enqueue(record *contract.Record, level contract.Level) {
+	overflow := r.logger.overflow
+	switch overflow.Policy {
+	case OverflowBlockTimeout:
+		select {
+		case r.queue <- record:
+			atomic.AddUint64(&r.enqueued, 1)
+			return
+		default:
+			break
+		}
+		timer := time.NewTimer(overflow.Timeout)
+		defer timer.Stop()
+		select {
+		case r.queue <- record:
+			atomic.AddUint64(&r.enqueued, 1)
+		case <-timer.C:
+			r.drop(record)
+		case <-r.done:
+			r.drop(record)
+		}
+	case OverflowDropNewest:
+		select {
+		case r.queue <- record:
+			atomic.AddUint64(&r.enqueued, 1)
+		default:
+			r.drop(record)
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case r.queue <- record:
+				atomic.AddUint64(&r.enqueued, 1)
+				return
+			default:
+				break
+			}
+			select {
+			case old := <-r.queue:
+				atomic.AddUint64(&r.dequeued, 1)
+				r.drop(old)
+			default:
+				break
+			}
+		}
+	case OverflowDropBelow:
+		if level <= overflow.Level {
+			r.block(record)
+			return
+		}
+		select {
+		case r.queue <- record:
+			atomic.AddUint64(&r.enqueued, 1)
+		default:
+			r.drop(record)
+		}
+	default:
+		r.block(record)
+	}
+}
+
+// 阻塞等待队列空闲
+func (r *worker) block(record *contract.Record) {
+	select {
+	case r.queue <- record:
+		atomic.AddUint64(&r.enqueued, 1)
+	case <-r.done:
+		r.drop(record)
+	}
+}
+
+// 记录被丢弃的日志，并释放队列持有的引用
+func (r *worker) drop(record *contract.Record) {
+	level := contract.GetLevelByName(record.Level)
+	r.logger.drop(level)
+	metrics.Dropped.Add(record.Channel, int(level), 1)
+	atomic.AddUint64(&r.droppedPending, 1)
+	record.Release()
+}
+
+// 队列恢复空闲后，处理一条丢弃日志数量的汇总日志
+func (r *worker) handleDropped() {
+	if atomic.LoadUint64(&r.droppedPending) == 0 || len(r.queue) > 0 {
+		return
+	}
+	n := atomic.SwapUint64(&r.droppedPending, 0)
+	if n == 0 {
+		return
+	}
+	record := contract.AcquireRecord()
+	defer record.Release()
+	record.Channel = r.logger.channel
+	record.Level = contract.GetNameByLevel(contract.LevelWarning)
+	record.Message = fmt.Sprintf("%d records dropped by async queue overflow", n)
+	record.Extra["Dropped"] = n
+	if r.handler.IsHandling(contract.LevelWarning) {
+		r.call(record)
+	}
+}
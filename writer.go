@@ -0,0 +1,134 @@
+package flog
+
+import (
+	"bytes"
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/internal/report"
+	"io"
+	libLog "log"
+	"strings"
+	"sync"
+)
+
+func init() {
+	report.Redirected = func(w io.Writer) bool {
+		_, ok := w.(*Writer)
+		return ok
+	}
+	report.Reflux = func(w io.Writer, message string) bool {
+		writer, ok := w.(*Writer)
+		if ok {
+			writer.reflux(message)
+		}
+		return ok
+	}
+}
+
+// 未换行的内容超过该长度时，不再等待换行，直接写为一条日志
+const maxWriterLine = 64 << 10
+
+// Writer 将写入的内容按行转换为指定等级的日志
+// 可以作为标准库 log、http.Server.ErrorLog 等 *log.Logger 的输出
+type Writer struct {
+	logger *Logger
+	//标记了回流的内部错误的日志收集器
+	internal *Logger
+	level    contract.Level
+	lock     *sync.Mutex
+	//尚未换行的内容
+	buf []byte
+}
+
+func NewWriter(logger *Logger, level contract.Level) *Writer {
+	tmp := new(Writer)
+	tmp.logger = logger
+	tmp.internal = logger.With(report.Key, true)
+	tmp.level = level
+	tmp.lock = new(sync.Mutex)
+	return tmp
+}
+
+// Write 每个换行符结束一条日志，未换行的内容等待下次写入，日志的调用位置是标准库 log 的调用方
+// 处理写入的日志时产生的内部错误直接写入标准错误，不会再次回流到本方法
+func (r *Writer) Write(p []byte) (n int, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	logger := caller(r.logger)
+	n = len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			r.buf = append(r.buf, p...)
+			if len(r.buf) >= maxWriterLine {
+				r.emit(logger, r.buf)
+				r.buf = r.buf[:0]
+			}
+			break
+		}
+		if len(r.buf) > 0 {
+			r.buf = append(r.buf, p[:i]...)
+			r.emit(logger, r.buf)
+			r.buf = r.buf[:0]
+		} else {
+			r.emit(logger, p[:i])
+		}
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+// Sync 将尚未换行的内容写为一条日志
+func (r *Writer) Sync() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if len(r.buf) > 0 {
+		r.emit(caller(r.logger), r.buf)
+		r.buf = r.buf[:0]
+	}
+	return nil
+}
+
+// 将回流的内部错误按行写为标记了回流的内部错误的日志，不与尚未换行的内容合并
+func (r *Writer) reflux(message string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, line := range strings.Split(message, "\n") {
+		r.emit(r.internal, []byte(line))
+	}
+}
+
+// 返回通过重定向写入日志的子日志收集器，并绑定调用位置，调用位置是 Write 或者 Sync 的调用方，跳过标准库 log 的函数
+// 必须由 Write 或者 Sync 直接调用
+func caller(logger *Logger) *Logger {
+	tmp := logger.child(logger.channel)
+	tmp.redirected = true
+	//跳过 caller 与 Write 或者 Sync
+	tmp.caller = callerFrame(2, "log.")
+	return tmp
+}
+
+func (r *Writer) emit(logger *Logger, line []byte) {
+	line = bytes.TrimSuffix(line, []byte{'\r'})
+	if len(line) == 0 {
+		return
+	}
+	logger.addRecord(nil, r.level, false, string(line), nil, nil)
+}
+
+// NewStdLog 创建输出到日志收集器的 *log.Logger，比如用作 http.Server.ErrorLog
+func NewStdLog(logger *Logger, level contract.Level) *libLog.Logger {
+	return libLog.New(NewWriter(logger, level), "", 0)
+}
+
+// RedirectStdLog 将标准库 log 的输出重定向到日志收集器，日志时间由日志收集器记录，所以会清除标准库 log 的标志
+// 返回的函数用于恢复重定向之前的输出与标志
+func RedirectStdLog(logger *Logger, level contract.Level) func() {
+	flags := libLog.Flags()
+	output := libLog.Writer()
+	libLog.SetFlags(0)
+	libLog.SetOutput(NewWriter(logger, level))
+	return func() {
+		libLog.SetFlags(flags)
+		libLog.SetOutput(output)
+	}
+}
@@ -0,0 +1,164 @@
+package flog_test
+
+import (
+	"github.com/buexplain/go-flog"
+	"github.com/buexplain/go-flog/contract"
+	"github.com/buexplain/go-flog/extra"
+	"github.com/buexplain/go-flog/internal/report"
+	"log"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWriter(t *testing.T) {
+	memory := newMemoryHandler(contract.LevelDebug)
+	logger := flog.New("writer", memory)
+	w := flog.NewWriter(logger, contract.LevelWarning)
+	_, _ = w.Write([]byte("first\nsec"))
+	_, _ = w.Write([]byte("ond\r\n\nthird"))
+	_ = w.Sync()
+	flog.NewStdLog(logger, contract.LevelError).Printf("server %s", "error")
+	records := memory.getRecords()
+	expect := []string{"first", "second", "third", "server error"}
+	if len(records) != len(expect) {
+		t.Fatalf("期待收集到 %d 条日志，实际收集到 %d 条", len(expect), len(records))
+	}
+	for i, v := range expect {
+		if records[i].Message != v {
+			t.Errorf("第 %d 条日志期待为 %s，实际为 %s", i, v, records[i].Message)
+		}
+	}
+	if records[0].Level != "warning" || records[3].Level != "error" {
+		t.Error("日志等级错误", records[0].Level, records[3].Level)
+	}
+}
+
+func TestWriterCaller(t *testing.T) {
+	memory := newMemoryHandler(contract.LevelDebug)
+	logger := flog.New("writer", memory, extra.NewFuncCaller())
+	w := flog.NewWriter(logger, contract.LevelWarning)
+	_, _, line, _ := runtime.Caller(0)
+	_, _ = w.Write([]byte("write\npending"))
+	_ = w.Sync()
+	flog.NewStdLog(logger, contract.LevelError).Print("std")
+	records := memory.getRecords()
+	if len(records) != 3 {
+		t.Fatalf("期待收集到 3 条日志，实际收集到 %d 条", len(records))
+	}
+	//日志的调用位置是 Write、Sync 或者标准库 log 的调用方
+	for i, record := range records {
+		if file, _ := record.Extra["File"].(string); !strings.HasSuffix(file, "writer_test.go") || record.Extra["Line"] != line+1+i {
+			t.Error("日志的调用位置错误", record.Extra["File"], record.Extra["Line"], line+1+i)
+		}
+	}
+}
+
+// 处理日志时恐慌的日志处理器
+type panicHandler struct {
+	count int32
+}
+
+func (r *panicHandler) Handle(record *contract.Record) bool {
+	atomic.AddInt32(&r.count, 1)
+	panic("panic: " + record.Message)
+}
+
+func (r *panicHandler) IsHandling(level contract.Level) bool {
+	return true
+}
+
+func (r *panicHandler) Close() error {
+	return nil
+}
+
+func TestRedirectStdLog(t *testing.T) {
+	memory := newMemoryHandler(contract.LevelDebug)
+	logger := flog.New("std", memory)
+	restore := flog.RedirectStdLog(logger, contract.LevelInfo)
+	log.Println("hello")
+	restore()
+	log.SetOutput(new(strings.Builder))
+	log.Println("restored")
+	restore()
+	if records := memory.getRecords(); len(records) != 1 || records[0].Message != "hello" || records[0].Level != "info" {
+		t.Error("标准库 log 没有重定向到日志收集器", records)
+	}
+	//日志处理器的内部错误经重定向回流到同一个日志收集器，不会无限递归
+	for _, async := range []bool{false, true} {
+		panicky := &panicHandler{}
+		logger = flog.New("std", panicky)
+		if async {
+			logger.Async(10)
+		}
+		restore = flog.RedirectStdLog(logger, contract.LevelError)
+		log.Println("redirect")
+		logger.Info("boom")
+		time.Sleep(100 * time.Millisecond)
+		restore()
+		_ = logger.Close(10 * time.Millisecond)
+		//处理通过重定向写入的日志时产生的错误直接写入标准错误，其余日志的错误最多回流一次
+		expect := int32(3)
+		if n := atomic.LoadInt32(&panicky.count); n != expect {
+			t.Errorf("内部错误回流次数错误，期待日志处理器被调用 %d 次，实际调用 %d 次", expect, n)
+		}
+	}
+	//其它日志收集器的内部错误回流到重定向的日志收集器，并带有标记
+	memory = newMemoryHandler(contract.LevelDebug)
+	restore = flog.RedirectStdLog(flog.New("std", memory), contract.LevelError)
+	defer restore()
+	flog.New("other", &panicHandler{}).Info("boom")
+	deadline := time.Now().Add(time.Second)
+	for len(memory.getRecords()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if records := memory.getRecords(); len(records) != 1 || !strings.HasSuffix(records[0].Message, "panic: boom") || records[0].Extra[report.Key] != true {
+		t.Error("内部错误没有回流到日志处理器", records)
+	}
+}
+
+func TestRedirectStdLogConcurrent(t *testing.T) {
+	memory := newMemoryHandler(contract.LevelDebug)
+	restore := flog.RedirectStdLog(flog.New("std", memory), contract.LevelError)
+	defer restore()
+	//一个go程不断产生回流的内部错误，另一个go程同时写入标准库 log
+	other := flog.New("other", &panicHandler{})
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			other.Info("boom")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			log.Println("user")
+		}
+	}()
+	wg.Wait()
+	deadline := time.Now().Add(time.Second)
+	for len(memory.getRecords()) < 200 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	users := 0
+	for _, record := range memory.getRecords() {
+		_, internal := record.Extra[report.Key]
+		if record.Message == "user" {
+			users++
+			if internal {
+				t.Error("其它go程写入标准库 log 的日志不应该被标记为内部错误")
+			}
+		} else if !internal {
+			t.Error("回流的内部错误没有被标记", record.Message)
+		}
+	}
+	if users != 100 {
+		t.Errorf("期待收集到 100 条标准库 log 的日志，实际收集到 %d 条", users)
+	}
+	_ = other.Close()
+}